@@ -0,0 +1,191 @@
+package gometalint
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// minAggregated is the number of comments of a linter in a file
+// they are collapsed from
+const minAggregated = 3
+
+// aggregation collapses repeated comments and limits number of comments
+type aggregation struct {
+	enabled bool
+	// maxInFile is the maximum number of comments in a file, 0 for no limit
+	maxInFile int
+	// maxTotal is the maximum number of comments in a review, 0 for no limit
+	maxTotal int
+}
+
+// aggregationConfig reads aggregation options from the configuration:
+// "aggregate" to enable collapsing, "maxCommentsPerFile" and "maxComments";
+// all of them are off by default
+func aggregationConfig(logger log.Logger, s types.Struct) aggregation {
+	var a aggregation
+
+	config := s.GetFields()
+	if v, ok := config["aggregate"]; ok && v != nil {
+		if b, ok := v.GetKind().(*types.Value_BoolValue); ok {
+			a.enabled = b.BoolValue
+		} else {
+			logger.Warningf("wrong type for aggregate argument")
+		}
+	}
+
+	limits := []struct {
+		name  string
+		value *int
+	}{
+		{"maxCommentsPerFile", &a.maxInFile},
+		{"maxComments", &a.maxTotal},
+	}
+
+	for _, l := range limits {
+		v, ok := config[l.name]
+		if !ok || v == nil {
+			continue
+		}
+
+		n, ok := intValue(v)
+		if !ok {
+			logger.Warningf("wrong type for %s argument", l.name)
+			continue
+		}
+
+		if n < 0 {
+			n = 0
+		}
+
+		*l.value = n
+	}
+
+	return a
+}
+
+// fileLinter identifies comments of one linter in one file
+type fileLinter struct {
+	file   string
+	linter string
+}
+
+// apply collapses comments and drops ones over the limits, order of comments
+// is kept. The global comment summarizing changes is returned, nil if
+// the comments are returned as is.
+func (a aggregation) apply(comments []Comment) ([]Comment, *pb.Comment) {
+	var summary []string
+
+	if a.enabled {
+		comments, summary = a.collapse(comments)
+	}
+
+	inFile := make(map[string]int)
+	overInFile := make(map[string]int)
+	var files []string
+	var result []Comment
+	overTotal := 0
+	for _, c := range comments {
+		if a.maxInFile > 0 && inFile[c.file] >= a.maxInFile {
+			if overInFile[c.file] == 0 {
+				files = append(files, c.file)
+			}
+			overInFile[c.file]++
+			continue
+		}
+
+		if a.maxTotal > 0 && len(result) >= a.maxTotal {
+			overTotal++
+			continue
+		}
+
+		inFile[c.file]++
+		result = append(result, c)
+	}
+
+	for _, file := range files {
+		summary = append(summary, fmt.Sprintf(
+			"- %d issues in `%s` over the limit of %d comments per file",
+			overInFile[file], file, a.maxInFile))
+	}
+
+	if overTotal > 0 {
+		summary = append(summary, fmt.Sprintf(
+			"- %d issues over the limit of %d comments per review", overTotal, a.maxTotal))
+	}
+
+	if len(summary) == 0 {
+		return result, nil
+	}
+
+	text := "Some issues are not posted as separate comments:\n" + strings.Join(summary, "\n")
+	return result, &pb.Comment{Text: text}
+}
+
+// collapse replaces comments of a linter in a file with one comment
+// if there are at least minAggregated of them
+func (a aggregation) collapse(comments []Comment) ([]Comment, []string) {
+	groups := make(map[fileLinter][]Comment)
+	for _, c := range comments {
+		key := fileLinter{c.file, c.Linter()}
+		groups[key] = append(groups[key], c)
+	}
+
+	collapsed := make(map[fileLinter]bool)
+	var result []Comment
+	var summary []string
+	for _, c := range comments {
+		key := fileLinter{c.file, c.Linter()}
+		group := groups[key]
+		if key.linter == "" || len(group) < minAggregated {
+			result = append(result, c)
+			continue
+		}
+
+		if collapsed[key] {
+			continue
+		}
+
+		collapsed[key] = true
+		result = append(result, collapseComments(key.linter, group))
+		summary = append(summary, fmt.Sprintf(
+			"- %d issues of %s in `%s` are collapsed into one comment",
+			len(group), key.linter, key.file))
+	}
+
+	return result, summary
+}
+
+// collapseComments returns one comment on the first line
+// listing lines and distinct messages of the comments
+func collapseComments(linter string, comments []Comment) Comment {
+	comments = append([]Comment(nil), comments...)
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].lino < comments[j].lino
+	})
+
+	var lines, messages []string
+	seenLines := make(map[int32]bool)
+	seenMessages := make(map[string]bool)
+	for _, c := range comments {
+		if !seenLines[c.lino] {
+			seenLines[c.lino] = true
+			lines = append(lines, strconv.Itoa(int(c.lino)))
+		}
+
+		if msg := c.Message(); !seenMessages[msg] {
+			seenMessages[msg] = true
+			messages = append(messages, msg)
+		}
+	}
+
+	first := comments[0]
+	text := fmt.Sprintf(" %d issues on lines %s: %s (%s)",
+		len(comments), strings.Join(lines, ", "), strings.Join(messages, "; "), linter)
+	return NewComment(first.level, first.file, first.lino, first.col, text)
+}
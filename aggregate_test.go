@@ -0,0 +1,87 @@
+package gometalint
+
+import (
+	"fmt"
+	"testing"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestAggregationConfig(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(aggregation{}, aggregationConfig(logger, types.Struct{}),
+		"aggregation must be off by default")
+
+	require.Equal(aggregation{enabled: true, maxInFile: 5, maxTotal: 0},
+		aggregationConfig(logger, *pb.ToStruct(map[string]interface{}{
+			"aggregate":          true,
+			"maxCommentsPerFile": "5",
+			"maxComments":        -1,
+		})))
+
+	require.Equal(aggregation{},
+		aggregationConfig(logger, *pb.ToStruct(map[string]interface{}{
+			"aggregate":          "no",
+			"maxCommentsPerFile": 5.5,
+			"maxComments":        true,
+		})), "wrong values must be ignored")
+}
+
+func TestAggregationCollapse(t *testing.T) {
+	require := require.New(t)
+
+	comments := []Comment{
+		NewComment("warning", "a.go", 12, 1, " line is 130 characters (lll)"),
+		NewComment("warning", "a.go", 3, 1, ` "langauge" is a misspelling of "language" (misspell)`),
+		NewComment("warning", "a.go", 8, 1, " line is 120 characters (lll)"),
+		NewComment("warning", "b.go", 1, 1, " line is 120 characters (lll)"),
+		NewComment("warning", "a.go", 20, 1, " line is 120 characters (lll)"),
+		NewComment("warning", "a.go", 1, 0, " no linter"),
+		NewComment("warning", "a.go", 2, 0, " no linter"),
+		NewComment("warning", "a.go", 4, 0, " no linter"),
+	}
+
+	a := aggregation{enabled: true}
+	result, summary := a.apply(comments)
+	require.Equal([]Comment{
+		NewComment("warning", "a.go", 8, 1,
+			" 3 issues on lines 8, 12, 20: line is 120 characters; line is 130 characters (lll)"),
+		comments[1], comments[3], comments[5], comments[6], comments[7],
+	}, result)
+	require.Equal(&pb.Comment{Text: "Some issues are not posted as separate comments:\n" +
+		"- 3 issues of lll in `a.go` are collapsed into one comment"}, summary)
+
+	a.enabled = false
+	result, summary = a.apply(comments)
+	require.Equal(comments, result)
+	require.Nil(summary)
+}
+
+func TestAggregationLimits(t *testing.T) {
+	require := require.New(t)
+
+	var comments []Comment
+	for i := 1; i <= 5; i++ {
+		for _, file := range []string{"a.go", "b.go", "c.go"} {
+			comments = append(comments, NewComment("warning", file, int32(i), 0,
+				fmt.Sprintf(" issue %d (fake%d)", i, i)))
+		}
+	}
+
+	a := aggregation{enabled: true, maxInFile: 3, maxTotal: 7}
+	result, summary := a.apply(comments)
+	require.Len(result, 7)
+
+	inFile := make(map[string]int)
+	for _, c := range result {
+		inFile[c.file]++
+	}
+	require.Equal(map[string]int{"a.go": 3, "b.go": 2, "c.go": 2}, inFile)
+
+	require.Equal(&pb.Comment{Text: "Some issues are not posted as separate comments:\n" +
+		"- 2 issues in `a.go` over the limit of 3 comments per file\n" +
+		"- 6 issues over the limit of 7 comments per review"}, summary)
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"math"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -16,13 +17,21 @@ import (
 	"gopkg.in/src-d/lookout-sdk.v0/pb"
 )
 
-const artificialSep = "___.___"
-
 // Analyzer for the lookout
 type Analyzer struct {
 	Version    string
 	DataClient pb.DataClient
 	Args       []string
+	// Sandbox to run linters in, linters run unrestricted if it's nil
+	Sandbox *Sandbox
+	// Limits of files analyzed in one review
+	Limits Limits
+	// Concurrency is the number of lint jobs running at the same time,
+	// the number of CPUs if it's not positive
+	Concurrency int
+	// Profile holds the server-wide profile read at the start of every review,
+	// Limits and Concurrency are used if it's nil
+	Profile *ProfileStore
 }
 
 var _ pb.AnalyzerServer = &Analyzer{}
@@ -30,29 +39,78 @@ var _ pb.AnalyzerServer = &Analyzer{}
 // function to convert pb.types.Value to string argument
 type argumentConstructor func(logger log.Logger, v *types.Value) string
 
+// linterOption is an option of a linter converted to gometalint argument
+type linterOption struct {
+	schema   valueSchema
+	argument argumentConstructor
+}
+
 // map of linters with options and argument constructors
-var lintersOptions = map[string]map[string]argumentConstructor{
-	"lll": map[string]argumentConstructor{
-		"maxLen": func(logger log.Logger, v *types.Value) string {
-			var number int
-
-			switch v.GetKind().(type) {
-			case *types.Value_StringValue:
-				n, err := strconv.Atoi(v.GetStringValue())
-				if err != nil {
-					logger.Warningf("wrong type for lll:maxLen argument")
-					return ""
-				}
-				number = n
-			case *types.Value_NumberValue:
-				intpart, frac := math.Modf(v.GetNumberValue())
-				if frac != 0 {
-					logger.Warningf("wrong type for lll:maxLen argument")
-					return ""
-				}
-				number = int(intpart)
-			default:
-				logger.Warningf("wrong type for lll:maxLen argument")
+var lintersOptions = map[string]map[string]linterOption{
+	"lll": map[string]linterOption{
+		"maxLen": positiveIntOption("lll:maxLen", "--line-length=%d"),
+	},
+	"gocyclo": map[string]linterOption{
+		"over": positiveIntOption("gocyclo:over", "--cyclo-over=%d"),
+	},
+	"goimports": map[string]linterOption{
+		"localPrefix": stringOption("goimports:localPrefix", localPrefixSchema,
+			`--linter=goimports:goimports -l -local %s:^(?P<path>.*?\.go)$`),
+	},
+	"misspell": map[string]linterOption{
+		"locale": stringOption("misspell:locale", enumSchema{"US", "UK"}, "--misspell-locale=%s"),
+		"ignoreWords": wordsOption("misspell:ignoreWords",
+			`--linter=misspell:misspell -j 1 --locale "{misspelllocale}" -i %s:PATH:LINE:COL:MESSAGE`),
+	},
+}
+
+// localPrefixSchema accepts comma-separated import path prefixes,
+// empty string disables the default prefix
+var localPrefixSchema = stringSchema{check: func(s string) error {
+	if s != "" && !localPrefixRe.MatchString(s) {
+		return fmt.Errorf("must be comma-separated import paths")
+	}
+
+	return nil
+}}
+
+// localPrefixRe matches comma-separated import paths
+var localPrefixRe = regexp.MustCompile(`^[\w.~/-]+(,[\w.~/-]+)*$`)
+
+// wordSchema accepts single words
+var wordSchema = stringSchema{check: func(s string) error {
+	if !wordRe.MatchString(s) {
+		return fmt.Errorf("must be a word")
+	}
+
+	return nil
+}}
+
+// wordRe matches a single word
+var wordRe = regexp.MustCompile(`^[\pL-]+$`)
+
+// linterOptions returns options of the default or custom linter by name
+func linterOptions(name string) map[string]linterOption {
+	if opts, ok := lintersOptions[name]; ok {
+		return opts
+	}
+
+	if l, ok := customLinter(name); ok {
+		return l.options()
+	}
+
+	return nil
+}
+
+// positiveIntOption returns the option with an integer value,
+// the option is ignored if the number is less than 1
+func positiveIntOption(option, format string) linterOption {
+	return linterOption{
+		schema: intSchema{min: 1},
+		argument: func(logger log.Logger, v *types.Value) string {
+			number, ok := intValue(v)
+			if !ok {
+				logger.Warningf("wrong type for %s argument", option)
 				return ""
 			}
 
@@ -60,9 +118,102 @@ var lintersOptions = map[string]map[string]argumentConstructor{
 				return ""
 			}
 
-			return fmt.Sprintf("--line-length=%d", number)
+			return fmt.Sprintf(format, number)
 		},
-	},
+	}
+}
+
+// stringOption returns the option with a string value accepted by the schema,
+// the option is ignored if the value is empty or wrong
+func stringOption(option string, schema valueSchema, format string) linterOption {
+	return linterOption{
+		schema: schema,
+		argument: func(logger log.Logger, v *types.Value) string {
+			str, ok := v.GetKind().(*types.Value_StringValue)
+			if !ok {
+				logger.Warningf("wrong type for %s argument", option)
+				return ""
+			}
+
+			if str.StringValue == "" {
+				return ""
+			}
+
+			if errs := schema.validate(option, v); len(errs) > 0 {
+				logger.Warningf("wrong value for %s", errs[0])
+				return ""
+			}
+
+			return fmt.Sprintf(format, str.StringValue)
+		},
+	}
+}
+
+// wordsOption returns the option with a list of words joined by commas,
+// the option is ignored if the list is empty or has wrong words
+func wordsOption(option, format string) linterOption {
+	schema := listSchema{items: wordSchema}
+	return linterOption{
+		schema: schema,
+		argument: func(logger log.Logger, v *types.Value) string {
+			list, ok := v.GetKind().(*types.Value_ListValue)
+			if !ok {
+				logger.Warningf("wrong type for %s argument", option)
+				return ""
+			}
+
+			if errs := schema.validate(option, v); len(errs) > 0 {
+				logger.Warningf("wrong value for %s", errs[0])
+				return ""
+			}
+
+			var words []string
+			for _, item := range list.ListValue.GetValues() {
+				words = append(words, item.GetStringValue())
+			}
+
+			if len(words) == 0 {
+				return ""
+			}
+
+			return fmt.Sprintf(format, strings.Join(words, ","))
+		},
+	}
+}
+
+// intValue converts a number or a string value to int,
+// false is returned if the value is not an integer
+func intValue(v *types.Value) (int, bool) {
+	switch v.GetKind().(type) {
+	case *types.Value_StringValue:
+		n, err := strconv.Atoi(v.GetStringValue())
+		if err != nil {
+			return 0, false
+		}
+		return n, true
+	case *types.Value_NumberValue:
+		intpart, frac := math.Modf(v.GetNumberValue())
+		if frac != 0 {
+			return 0, false
+		}
+		return int(intpart), true
+	default:
+		return 0, false
+	}
+}
+
+// numberValue converts a number or a string value to float64,
+// false is returned if the value is not a number
+func numberValue(v *types.Value) (float64, bool) {
+	switch v.GetKind().(type) {
+	case *types.Value_StringValue:
+		n, err := strconv.ParseFloat(v.GetStringValue(), 64)
+		return n, err == nil
+	case *types.Value_NumberValue:
+		return v.GetNumberValue(), true
+	default:
+		return 0, false
+	}
 }
 
 func (a *Analyzer) NotifyReviewEvent(ctx context.Context, e *pb.ReviewEvent) (
@@ -70,27 +221,72 @@ func (a *Analyzer) NotifyReviewEvent(ctx context.Context, e *pb.ReviewEvent) (
 
 	logger := log.With(log.Fields(pb.GetLogFields(ctx)))
 
-	changes, err := a.DataClient.GetChanges(ctx, &pb.ChangesRequest{
+	configErrs := ValidateConfig(e.Configuration)
+	for _, err := range configErrs {
+		logger.Warningf("wrong configuration: %s", err)
+	}
+
+	// precedence of settings from the lowest: the import path of the repository,
+	// the profile, matching repository profiles, the repository linters
+	// configuration file, the analyzer configuration
+	profile := a.profile()
+	defaultLinters, defaults := profile.repositoryDefaults(logger, e.Head.InternalRepositoryURL)
+	conf := mergeSettings(defaults, e.Configuration)
+
+	repo := fetchRepoConfig(ctx, logger, a.DataClient, &e.Head)
+	paths := pathsConfig(logger, conf)
+	changes, err := newChangesStream(ctx, logger, a.DataClient, &pb.ChangesRequest{
 		Head:             &e.Head,
 		Base:             &e.Base,
 		WantContents:     true,
 		WantUAST:         false,
 		ExcludeVendored:  true,
 		IncludeLanguages: []string{"go"},
+		IncludePattern:   paths.includePattern(),
+		ExcludePattern:   paths.excludePattern(),
 	})
 	if err != nil {
 		logger.Errorf(err, "failed to GetChanges from a DataService")
 		return nil, err
 	}
 
-	tmp, err := ioutil.TempDir("", "gometalint")
+	importPath := repoImportPath(ctx, logger, a.DataClient, &e.Head)
+	ws, err := newWorkspace(profile.Limits, importPath)
 	if err != nil {
 		logger.Errorf(err, "cannot create tmp dir in %s", os.TempDir())
 		return nil, err
 	}
-	defer os.RemoveAll(tmp)
-	logger.Debugf("Saving files to '%s'", tmp)
+	defer ws.Close()
+	logger.Debugf("Saving files to '%s'", ws.root)
+
+	generated := generatedConfig(logger, conf)
+	groups := newLintGroups(logger, conf, importPathSettings(importPath).
+		merge(defaultLinters).merge(repo.linterSettings()))
+	withSummary := summaryConfig(logger, conf)
+	newIssues := newIssuesConfig(logger, conf)
+	stats := newReviewSummary()
+	bases := newBaseIndex()
+
+	sem := make(chan struct{}, profile.concurrency())
+	pipe := newPipeline(ctx, logger, ws, groups, a.linter(logger, ws, profile, paths, repo), sem)
+	defer pipe.Close()
+
+	// base revisions of files are linted in a separate workspace,
+	// as they are saved with paths of the head revisions
+	var basePipe *pipeline
+	if newIssues.enabled {
+		baseWs, err := newWorkspace(profile.Limits, importPath)
+		if err != nil {
+			logger.Errorf(err, "cannot create tmp dir in %s", os.TempDir())
+			return nil, err
+		}
+		defer baseWs.Close()
 
+		basePipe = newPipeline(ctx, logger, baseWs, groups, a.linter(logger, baseWs, profile, paths, repo), sem)
+		defer basePipe.Close()
+	}
+
+	var streamErr error
 	found, saved := 0, 0
 	for {
 		change, err := changes.Recv()
@@ -99,8 +295,13 @@ func (a *Analyzer) NotifyReviewEvent(ctx context.Context, e *pb.ReviewEvent) (
 		}
 
 		if err != nil {
-			logger.Errorf(err, "failed to get a file from DataServer")
-			continue
+			if ctx.Err() != nil {
+				return nil, err
+			}
+
+			logger.Errorf(err, "failed to get a file from DataServer, analyzing received files")
+			streamErr = err
+			break
 		}
 
 		if change.Head == nil {
@@ -108,33 +309,103 @@ func (a *Analyzer) NotifyReviewEvent(ctx context.Context, e *pb.ReviewEvent) (
 		}
 
 		file := change.Head
-		if err = saveTo(file, tmp); err != nil {
-			logger.Errorf(err, "failed to write file %q", file.Path)
+		if !paths.matchFile(file.Path) || repo.skipFile(file.Path) {
+			logger.Debugf("skipping excluded file %q", file.Path)
+			stats.skip(skippedExcluded)
+			continue
+		}
+
+		if generated.isGenerated(file) {
+			logger.Debugf("skipping generated file %q", file.Path)
+			stats.skip(skippedGenerated)
+			continue
+		}
+
+		if err = pipe.Save(file); err != nil {
+			logger.Warningf("skipping file %q: %s", file.Path, err)
+			stats.skip(skippedNotSaved)
 		} else {
 			saved++
+			if withSummary || newIssues.enabled {
+				bases.add(change)
+			}
+
+			if basePipe != nil && change.Base != nil {
+				saveBase(logger, basePipe, change)
+			}
 		}
 		found++
 	}
+	stats.analyzed = saved
+
+	var allComments []*pb.Comment
+	if errsComment := configErrorsComment(configErrs); errsComment != nil {
+		allComments = append(allComments, errsComment)
+	}
+
+	if ignored := repo.ignoredComment(); ignored != nil {
+		allComments = append(allComments, ignored)
+	}
+
+	if streamErr != nil {
+		allComments = append(allComments, partialComment(streamErr))
+	}
 
 	if saved < found {
 		logger.Warningf("%d/%d Golang files saved. analyzer won't run on non-saved ones", saved, found)
+		allComments = append(allComments, skippedComment(ws.Skipped()))
 	}
+
+	var issues []Comment
 	if saved == 0 {
 		logger.Debugf("no Golang files to work on. skip running gometalinter")
-		return &pb.EventResponse{AnalyzerVersion: a.Version}, nil
+	} else {
+		logger.Debugf("%d Golang files to work on. waiting for gometalinter", saved)
+		issues, stats.lintTime = pipe.Wait()
+		if basePipe != nil {
+			baseIssues, baseTime := basePipe.Wait()
+			if baseTime > stats.lintTime {
+				stats.lintTime = baseTime
+			}
+
+			newOnes, oldOnes, fixed := matchIssues(issues, baseIssues, bases)
+			logger.Debugf("%d new issues, %d pre-existing, %d fixed",
+				len(newOnes), len(oldOnes), len(fixed))
+			stats.addIssues(newOnes, oldOnes)
+			stats.fixed = len(fixed)
+			issues = newOnes
+		} else {
+			stats.addIssues(splitByBase(issues, bases))
+		}
 	}
-	logger.Debugf("%d Golang files to work on. running gometalinter", saved)
 
-	withArgs := append(append(a.Args, tmp), a.linterArguments(logger, e.Configuration)...)
-	comments := RunGometalinter(withArgs)
-	var allComments []*pb.Comment
-	for _, comment := range comments {
-		origPathFile := revertOriginalPath(comment.file, tmp)
-		origPathText := revertOriginalPathIn(comment.text, tmp)
+	if !newIssues.praiseFixed {
+		stats.fixed = 0
+	}
+
+	if withSummary {
+		allComments = append([]*pb.Comment{stats.comment()}, allComments...)
+	} else if praise := stats.praiseComment(); praise != nil {
+		allComments = append([]*pb.Comment{praise}, allComments...)
+	}
+
+	issues, summary := aggregationConfig(logger, conf).apply(SortComments(issues))
+	if summary != nil {
+		allComments = append(allComments, summary)
+	}
+
+	format := commentFormatConfig(logger, conf)
+	fingerprints := Fingerprints(issues)
+	for i, issue := range issues {
+		text := format.text(issue)
+		if format == formatMarkdown {
+			text = withFingerprint(text, fingerprints[i])
+		}
+
 		newComment := pb.Comment{
-			File: origPathFile,
-			Line: comment.lino,
-			Text: origPathText,
+			File: issue.file,
+			Line: issue.lino,
+			Text: text,
 		}
 		allComments = append(allComments, &newComment)
 		logger.Debugf("Get comment %v", newComment)
@@ -147,111 +418,93 @@ func (a *Analyzer) NotifyReviewEvent(ctx context.Context, e *pb.ReviewEvent) (
 	}, nil
 }
 
-// flattenPath flattens relative path and puts it inside tmp.
-func flattenPath(file string, tmp string) string {
-	nFile := strings.Join(strings.Split(file, string(os.PathSeparator)), artificialSep)
-	nPath := path.Join(tmp, nFile)
-	return nPath
-}
-
-// revertOriginalPath reverses origina path from a flat one.
-func revertOriginalPath(file string, tmp string) string {
-	//TrimLeft(, tmp) but works for rel paths
-	noTmpfile := file[strings.Index(file, tmp)+len(tmp):]
-	origPathFile := strings.TrimLeft(
-		path.Join(strings.Split(noTmpfile, artificialSep)...),
-		string(os.PathSeparator))
-	return origPathFile
+// saveBase saves the base revision of the changed file
+// with the path of the head revision
+func saveBase(logger log.Logger, pipe *pipeline, change *pb.Change) {
+	path := change.Head.Path
+	base := &pb.File{Path: path, Mode: change.Base.Mode, Content: change.Base.Content}
+	if err := pipe.Save(base); err != nil {
+		logger.Warningf("skipping base revision of file %q: %s", path, err)
+	}
 }
 
-// revertOriginalPathIn a given text, recovers original path in words
-// that have 'artificialSep'.
-func revertOriginalPathIn(text string, tmp string) string {
-	if strings.LastIndex(text, artificialSep) < 0 {
-		return text
-	}
-	var words []string
-	for _, word := range strings.Fields(text) {
-		if strings.Index(word, artificialSep) >= 0 {
-			word = revertOriginalPath(word, tmp)
+// profile returns the current profile or the one with limits of the analyzer
+func (a *Analyzer) profile() *Profile {
+	if a.Profile != nil {
+		if p := a.Profile.Get(); p != nil {
+			return p
 		}
-		words = append(words, word)
 	}
-	return strings.Join(words, " ")
-}
-
-// saveTo saves a file to given dir, preserving it's original path.
-// In case of error it is returned. All files saved this way will
-// be in the root of the same dir.
-func saveTo(file *pb.File, tmp string) error {
-	flatPath := flattenPath(file.Path, tmp)
-	return ioutil.WriteFile(flatPath, file.Content, 0644)
-}
 
-func (a *Analyzer) NotifyPushEvent(ctx context.Context, e *pb.PushEvent) (*pb.EventResponse, error) {
-	return &pb.EventResponse{}, nil
+	return &Profile{Limits: a.Limits, Concurrency: a.Concurrency}
 }
 
-func (a *Analyzer) linterArguments(logger log.Logger, s types.Struct) []string {
-	config := s.GetFields()
-	if config == nil {
-		return nil
-	}
-
-	clStruct, ok := config["linters"]
-	if !ok || clStruct == nil {
-		return nil
-	}
-
-	lintersListValue := clStruct.GetListValue()
-	if lintersListValue == nil {
-		return nil
-	}
-
-	var args []string
-
-	for _, v := range lintersListValue.GetValues() {
-		if v == nil {
-			continue
+// linter returns function running gometalint on a directory of files saved
+// to the workspace, the issues have original paths
+func (a *Analyzer) linter(logger log.Logger, ws *workspace, profile *Profile,
+	paths pathFilter, repo *repoConfig) lintFunc {
+	return func(ctx context.Context, dir string, group *lintGroup) []Comment {
+		withArgs := append(append([]string(nil), a.Args...), profile.arguments()...)
+		withArgs = append(append(withArgs, dir), group.args...)
+		comments, err := runGometalinter(ctx, withArgs, a.Sandbox, ws.env(), ws.dir)
+		if err != nil {
+			logger.Errorf(err, "gometalinter failed, %d issues found", len(comments))
 		}
 
-		sv := v.GetStructValue()
-		if sv == nil {
-			continue
-		}
+		for _, cmd := range group.commands {
+			issues, err := cmd.run(ctx, dir, a.Sandbox, ws.env(), ws.dir)
+			if err != nil {
+				logger.Errorf(err, "%s failed, %d issues found", cmd.linter.Name, len(issues))
+			}
 
-		fields := sv.GetFields()
-		nameV, ok := fields["name"]
-		if !ok || nameV == nil {
-			continue
+			comments = append(comments, issues...)
 		}
 
-		name := nameV.GetStringValue()
-		correctLinter := false
-		for linter := range lintersOptions {
-			if name == linter {
-				correctLinter = true
+		var issues []Comment
+		for _, comment := range comments {
+			origPathFile := revertOriginalPath(comment.file, ws.root)
+			if !paths.keepComment(comment.Linter(), origPathFile) || !repo.keepComment(comment) {
+				logger.Debugf("skipping excluded comment %v", comment)
+				continue
 			}
-		}
 
-		if !correctLinter {
-			logger.Warningf("unknown linter %s", name)
-			continue
+			origPathText := revertOriginalPathIn(comment.text, ws.root)
+			issues = append(issues, NewComment(comment.level, origPathFile,
+				comment.lino, comment.col, origPathText))
 		}
 
-		linterOpts := lintersOptions[name]
-		for optionName := range linterOpts {
-			optV, ok := fields[optionName]
-			if !ok || optV == nil {
-				continue
-			}
+		return issues
+	}
+}
 
-			arg := linterOpts[optionName](logger, optV)
-			if arg != "" {
-				args = append(args, arg)
-			}
+// revertOriginalPath returns the slash-separated path of the file
+// relative to the root directory of the workspace
+func revertOriginalPath(file string, root string) string {
+	//TrimLeft(, root) but works for rel paths
+	noRootFile := file[strings.Index(file, root)+len(root):]
+	return strings.TrimLeft(path.Join(filepath.ToSlash(noRootFile)), "/")
+}
+
+// revertOriginalPathIn a given text, recovers original paths in words
+// that point inside the root directory of the workspace.
+func revertOriginalPathIn(text string, root string) string {
+	if !strings.Contains(text, root) {
+		return text
+	}
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if strings.Contains(word, root) {
+			word = revertOriginalPath(word, root)
 		}
+		words = append(words, word)
 	}
+	return strings.Join(words, " ")
+}
 
-	return args
+func (a *Analyzer) NotifyPushEvent(ctx context.Context, e *pb.PushEvent) (*pb.EventResponse, error) {
+	return &pb.EventResponse{}, nil
+}
+
+func (a *Analyzer) linterArguments(logger log.Logger, s types.Struct) []string {
+	return readLinterSettings(logger, s.GetFields()["linters"], nil).arguments(logger)
 }
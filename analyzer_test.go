@@ -1,11 +1,23 @@
 package gometalint
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"testing"
+	"time"
+
+	"github.com/src-d/lookout-gometalint-analyzer/datatest"
 
 	types "github.com/gogo/protobuf/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	log "gopkg.in/src-d/go-log.v1"
 	"gopkg.in/src-d/lookout-sdk.v0/pb"
 )
@@ -80,31 +92,914 @@ func TestArgsCorrect(t *testing.T) {
 	})))
 }
 
+func TestArgsGocyclo(t *testing.T) {
+	a := Analyzer{}
+	require.Equal(t, []string{"--cyclo-over=15"}, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{
+				"name": "gocyclo",
+				"over": 15,
+			},
+		},
+	})))
+
+	require.Empty(t, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{
+				"name": "gocyclo",
+				"over": 15.5,
+			},
+		},
+	})))
+}
+
+func TestArgsGoimports(t *testing.T) {
+	a := Analyzer{}
+	require.Equal(t, []string{`--linter=goimports:goimports -l -local github.com/src-d,gopkg.in/src-d:` +
+		`^(?P<path>.*?\.go)$`}, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{
+				"name":        "goimports",
+				"localPrefix": "github.com/src-d,gopkg.in/src-d",
+			},
+		},
+	})))
+
+	for _, prefix := range []interface{}{"", "github.com/a b", "a:b", 1} {
+		require.Empty(t, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+			"linters": []map[string]interface{}{
+				{
+					"name":        "goimports",
+					"localPrefix": prefix,
+				},
+			},
+		})), "prefix: %v", prefix)
+	}
+}
+
+func TestArgsMisspell(t *testing.T) {
+	a := Analyzer{}
+	require.Equal(t, []string{
+		`--linter=misspell:misspell -j 1 --locale "{misspelllocale}" -i colour,flavour:PATH:LINE:COL:MESSAGE`,
+		"--misspell-locale=UK",
+	}, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{
+				"name":        "misspell",
+				"locale":      "UK",
+				"ignoreWords": []string{"colour", "flavour"},
+			},
+		},
+	})))
+
+	require.Empty(t, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{
+				"name":        "misspell",
+				"locale":      "FR",
+				"ignoreWords": []string{"colour", "two words"},
+			},
+		},
+	})))
+
+	require.Empty(t, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{
+				"name":        "misspell",
+				"locale":      1,
+				"ignoreWords": []string{},
+			},
+		},
+	})))
+}
+
+func TestArgsEnabled(t *testing.T) {
+	a := Analyzer{}
+	require.Equal(t, []string{"--disable=lll"}, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{
+				"name":    "lll",
+				"enabled": false,
+				"maxLen":  120,
+			},
+		},
+	})))
+
+	require.Equal(t, []string{"--line-length=120"}, a.linterArguments(logger, *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{
+				"name":    "lll",
+				"enabled": "no",
+				"maxLen":  120,
+			},
+		},
+	})))
+}
+
 var pathTests = []struct {
 	in  string
 	out string
 }{
-	{"a/b.go", "/tmp/a___.___b.go"},
-	{"tmp/a/b.go", "/tmp/tmp___.___a___.___b.go"},
-	{"a/b/c/d/e.go", "/tmp/a___.___b___.___c___.___d___.___e.go"},
+	{"a/b.go", "/tmp/src/repository/a/b.go"},
+	{"tmp/a/b.go", "/tmp/src/repository/tmp/a/b.go"},
+	{"a/b/c/d/e.go", "/tmp/src/repository/a/b/c/d/e.go"},
 }
 
 func TestPathTransformations(t *testing.T) {
 	for _, tt := range pathTests {
 		t.Run(tt.in, func(t *testing.T) {
-			assert.Equal(t, tt.out, flattenPath(tt.in, "/tmp"))
-			assert.Equal(t, tt.in, revertOriginalPath(tt.out, "/tmp"))
+			assert.Equal(t, tt.in, revertOriginalPath(tt.out, "/tmp/src/repository"))
 		})
 	}
 }
 
 func TestPathInTextTransformations(t *testing.T) {
 	tmp := "/var/folders/rx/z9zyr71d70x92zwbn3rrjx4c0000gn/T/gometalint584398570"
-	text := "duplicate of /var/folders/rx/z9zyr71d70x92zwbn3rrjx4c0000gn/T/gometalint584398570/provider___.___github___.___poster_test.go:549-554 (dupl)"
+	text := "duplicate of /var/folders/rx/z9zyr71d70x92zwbn3rrjx4c0000gn/T/gometalint584398570/provider/github/poster_test.go:549-554 (dupl)"
 	expectedText := "duplicate of provider/github/poster_test.go:549-554 (dupl)"
 
 	newText := revertOriginalPathIn(text, tmp)
 	if newText != expectedText {
 		t.Fatalf("got %q, want %q", newText, expectedText)
 	}
+
+	text = "duplicate of /var/folders/rx/z9zyr71d70x92zwbn3rrjx4c0000gn/T/gometalint584398570/poster_test.go:549-554 (dupl)"
+	expectedText = "duplicate of poster_test.go:549-554 (dupl)"
+
+	newText = revertOriginalPathIn(text, tmp)
+	if newText != expectedText {
+		t.Fatalf("got %q, want %q", newText, expectedText)
+	}
+}
+
+// fakeLinter reports an issue on the first line of every Go file
+// in the directories it is given, mentioning the file path in the text
+const fakeLinter = `#!/bin/sh
+for arg in "$@"; do
+	if [ -d "$arg" ]; then
+		for f in "$arg"/*.go; do
+			echo "$f:1:2:warning: issue in $f (fake)"
+		done
+	fi
+done
+`
+
+// withFakeLinter replaces gometalinter binary with a shell script
+func withFakeLinter(t *testing.T, script string) func() {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake linter requires sh")
+	}
+
+	dir, err := ioutil.TempDir("", "gometalint-fake")
+	require.NoError(t, err)
+
+	fake := filepath.Join(dir, "gometalinter")
+	require.NoError(t, ioutil.WriteFile(fake, []byte(script), 0755))
+
+	origBin := bin
+	bin = fake
+	return func() {
+		bin = origBin
+		os.RemoveAll(dir)
+	}
+}
+
+func fixtureChanges(t *testing.T, names ...string) []*pb.Change {
+	changes, err := datatest.AddedFromDisk("_fixtures", names...)
+	require.NoError(t, err)
+	return changes
+}
+
+func review(t *testing.T, client pb.DataClient, conf map[string]interface{}) []*pb.Comment {
+	a := &Analyzer{Version: "test", DataClient: client}
+
+	e := &pb.ReviewEvent{}
+	if conf != nil {
+		e.Configuration = *pb.ToStruct(conf)
+	}
+
+	resp, err := a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(t, err)
+	require.Equal(t, "test", resp.AnalyzerVersion)
+	return resp.Comments
+}
+
+// fingerprintSuffix matches the fingerprint appended to texts of markdown comments
+var fingerprintSuffix = regexp.MustCompile(`\n\n<!-- fingerprint: ([0-9a-f]{32}) -->$`)
+
+func TestReviewFakeLinter(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, fakeLinter)()
+
+	changes := append(fixtureChanges(t, "gofmt_test.go", "lll_test.go"),
+		&pb.Change{Head: datatest.File("pkg/sub/a.go", "package sub\n")},
+		&pb.Change{Base: datatest.File("deleted.go", "package main\n")},
+		&pb.Change{Head: datatest.File("README.md", "# readme\n")},
+		&pb.Change{Head: datatest.File("vendor/dep/dep.go", "package dep\n")},
+	)
+	client := datatest.NewDataClient(changes...)
+
+	require.Equal([]*pb.Comment{
+		{File: "gofmt_test.go", Line: 1, Text: "issue in gofmt_test.go (fake)"},
+		{File: "lll_test.go", Line: 1, Text: "issue in lll_test.go (fake)"},
+		{File: "pkg/sub/a.go", Line: 1, Text: "issue in pkg/sub/a.go (fake)"},
+	}, review(t, client, nil))
+
+	reqs := client.ChangesRequests()
+	require.Len(reqs, 1)
+	require.True(reqs[0].WantContents)
+	require.True(reqs[0].ExcludeVendored)
+	require.Equal([]string{"go"}, reqs[0].IncludeLanguages)
+}
+
+func TestReviewLimits(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, fakeLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("big.go", "package big\n\nvar data = []byte{}\n")},
+		&pb.Change{Head: datatest.File("c.go", "package c\n")},
+	)
+	a := &Analyzer{DataClient: client, Limits: Limits{MaxFileSize: 20}}
+
+	resp, err := a.NotifyReviewEvent(context.Background(), &pb.ReviewEvent{})
+	require.NoError(err)
+	require.Equal([]*pb.Comment{
+		{Text: "1 files were not analyzed:\n- `big.go`: file is larger than 20 bytes"},
+		{File: "a.go", Line: 1, Text: "issue in a.go (fake)"},
+		{File: "c.go", Line: 1, Text: "issue in c.go (fake)"},
+	}, resp.Comments)
+
+	a.Limits = Limits{MaxFiles: -1, MaxTotalSize: 1}
+	resp, err = a.NotifyReviewEvent(context.Background(), &pb.ReviewEvent{})
+	require.NoError(err)
+	require.Len(resp.Comments, 1, "only skipped files comment is expected")
+}
+
+func TestReviewGenerated(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, fakeLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("a.pb.go", "package a\n")},
+		&pb.Change{Head: datatest.File("b.go", "// Code generated by hand. DO NOT EDIT.\npackage a\n")},
+		&pb.Change{Head: datatest.File("c_gen.go", "package a\n")},
+	)
+
+	require.Equal([]*pb.Comment{
+		{File: "a.go", Line: 1, Text: "issue in a.go (fake)"},
+	}, review(t, client, map[string]interface{}{
+		"generated": []string{"*_gen.go"},
+	}))
+
+	require.Len(review(t, client, map[string]interface{}{
+		"skipGenerated": false,
+	}), 4)
+}
+
+const pathsLinter = `#!/bin/sh
+for arg in "$@"; do
+	if [ -d "$arg" ]; then
+		for f in "$arg"/*.go; do
+			echo "$f:1:2:warning: long line (lll)"
+			echo "$f:2:2:warning: unsafe call (gosec)"
+		done
+	fi
+done
+`
+
+func TestReviewPaths(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, pathsLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("pkg/a.go", "package pkg\n")},
+		&pb.Change{Head: datatest.File("pkg/a_test.go", "package pkg\n")},
+		&pb.Change{Head: datatest.File("pkg/testdata/a.go", "package testdata\n")},
+		&pb.Change{Head: datatest.File("pkg/legacy/a.go", "package legacy\n")},
+	)
+
+	require.Equal([]*pb.Comment{
+		{File: "pkg/a.go", Line: 1, Text: " long line (lll)"},
+		{File: "pkg/a.go", Line: 2, Text: " unsafe call (gosec)"},
+		{File: "pkg/a_test.go", Line: 2, Text: " unsafe call (gosec)"},
+		{File: "pkg/testdata/a.go", Line: 1, Text: " long line (lll)"},
+	}, review(t, client, map[string]interface{}{
+		"include": `^pkg/`,
+		"exclude": `^pkg/legacy/`,
+		"linters": []map[string]interface{}{
+			{"name": "lll", "exclude": `_test\.go$`},
+			{"name": "gosec", "exclude": `(^|/)testdata/`},
+		},
+	}))
+
+	reqs := client.ChangesRequests()
+	require.Len(reqs, 1)
+	require.Equal(`^pkg/`, reqs[0].IncludePattern)
+	require.Equal(`^pkg/legacy/`, reqs[0].ExcludePattern)
+}
+
+// argsLinter reports linter options it was run with for every file
+const argsLinter = `#!/bin/sh
+args=""
+for arg in "$@"; do
+	case "$arg" in
+	--line-length=*|--cyclo-over=*|--disable=*|--deadline=*) args="$args $arg";;
+	esac
+done
+for arg in "$@"; do
+	if [ -d "$arg" ]; then
+		for f in "$arg"/*.go; do
+			echo "$f:1:0:warning: args$args (fake)"
+		done
+	fi
+done
+`
+
+func TestReviewOverrides(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, argsLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("cmd/tool/main.go", "package main\n")},
+		&pb.Change{Head: datatest.File("cmd/tool/main_test.go", "package main\n")},
+		&pb.Change{Head: datatest.File("pkg/a.go", "package pkg\n")},
+		&pb.Change{Head: datatest.File("pkg/a_test.go", "package pkg\n")},
+	)
+
+	require.Equal([]*pb.Comment{
+		{File: "a.go", Line: 1, Text: " args --cyclo-over=10 --line-length=120 (fake)"},
+		{File: "cmd/tool/main.go", Line: 1, Text: " args --disable=gocyclo --line-length=80 (fake)"},
+		{File: "cmd/tool/main_test.go", Line: 1, Text: " args --disable=gocyclo --disable=lll (fake)"},
+		{File: "pkg/a.go", Line: 1, Text: " args --cyclo-over=15 --line-length=120 (fake)"},
+		{File: "pkg/a_test.go", Line: 1, Text: " args --cyclo-over=15 --disable=lll (fake)"},
+	}, review(t, client, overridesConf))
+}
+
+func TestReviewRepoConfig(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, argsLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("testdata/a.go", "package testdata\n")},
+	)
+	client.Files = []*pb.File{datatest.File(".golangci.yml", `
+run:
+  skip-dirs: [testdata]
+  deadline: 5m
+linters:
+  disable: [gocyclo]
+linters-settings:
+  lll:
+    line-length: 100
+`)}
+
+	require.Equal([]*pb.Comment{
+		{Text: "Some options of `.golangci.yml` are ignored:\n- `run.deadline`: not supported"},
+		{File: "a.go", Line: 1, Text: " args --disable=gocyclo --line-length=120 (fake)"},
+	}, review(t, client, map[string]interface{}{
+		"linters": []map[string]interface{}{{"name": "lll", "maxLen": 120}},
+	}))
+}
+
+func TestReviewProfile(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, argsLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("b.go", "package b\n")},
+	)
+	client.Files = []*pb.File{datatest.File(".golangci.yml", `
+linters-settings:
+  lll:
+    line-length: 100
+`)}
+
+	profile := &Profile{
+		Linters: pb.ToValue([]map[string]interface{}{
+			{"name": "lll", "maxLen": 80},
+			{"name": "gocyclo", "over": 20},
+			{"name": "dupl", "enabled": false},
+		}),
+		Deadline: time.Minute,
+		Limits:   Limits{MaxFiles: 1},
+	}
+	a := &Analyzer{DataClient: client, Profile: NewProfileStore(profile)}
+
+	resp, err := a.NotifyReviewEvent(context.Background(), &pb.ReviewEvent{
+		Configuration: *pb.ToStruct(map[string]interface{}{
+			"linters": []map[string]interface{}{{"name": "gocyclo", "over": 15}},
+		}),
+	})
+	require.NoError(err)
+	require.Equal([]*pb.Comment{
+		{Text: "1 files were not analyzed:\n- `b.go`: limit of 1 files is reached"},
+		{File: "a.go", Line: 1, Text: " args --deadline=1m0s --disable=dupl --cyclo-over=15 --line-length=100 (fake)"},
+	}, resp.Comments)
+}
+
+func TestReviewRepositoryProfile(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, argsLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("cmd/main.go", "package main\n")},
+	)
+	client.Files = []*pb.File{datatest.File(".golangci.yml", `
+linters-settings:
+  lll:
+    line-length: 100
+`)}
+
+	profile := &Profile{
+		Linters: pb.ToValue([]map[string]interface{}{
+			{"name": "lll", "maxLen": 80},
+			{"name": "dupl", "enabled": false},
+		}),
+		Repositories: []RepositoryProfile{{
+			Repos: []string{"src-d/*"},
+			Settings: *pb.ToStruct(map[string]interface{}{
+				"linters": []map[string]interface{}{
+					{"name": "lll", "maxLen": 90},
+					{"name": "dupl", "enabled": true},
+					{"name": "gocyclo", "over": 20},
+				},
+				"overrides": []map[string]interface{}{{
+					"paths":   []string{"cmd"},
+					"linters": []map[string]interface{}{{"name": "gocyclo", "enabled": false}},
+				}},
+				"exclude": "^vendor/",
+			}),
+		}},
+	}
+	a := &Analyzer{DataClient: client, Profile: NewProfileStore(profile)}
+
+	e := &pb.ReviewEvent{
+		Configuration: *pb.ToStruct(map[string]interface{}{
+			"linters": []map[string]interface{}{{"name": "gocyclo", "over": 15}},
+			"exclude": "^cmd/",
+		}),
+	}
+	e.Head.InternalRepositoryURL = "https://github.com/src-d/lookout"
+
+	resp, err := a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal([]*pb.Comment{
+		{File: "a.go", Line: 1, Text: " args --cyclo-over=15 --line-length=100 (fake)"},
+	}, resp.Comments)
+
+	e.Configuration = types.Struct{}
+	resp, err = a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal([]*pb.Comment{
+		{File: "a.go", Line: 1, Text: " args --cyclo-over=20 --line-length=100 (fake)"},
+		{File: "cmd/main.go", Line: 1, Text: " args --disable=gocyclo --line-length=100 (fake)"},
+	}, resp.Comments)
+
+	e.Head.InternalRepositoryURL = "https://github.com/bblfsh/sdk"
+	resp, err = a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal([]*pb.Comment{
+		{File: "a.go", Line: 1, Text: " args --disable=dupl --line-length=100 (fake)"},
+		{File: "cmd/main.go", Line: 1, Text: " args --disable=dupl --line-length=100 (fake)"},
+	}, resp.Comments)
+}
+
+// layoutLinter reports the import path of the directories it is given
+// and the environment related to GOPATH for every file
+const layoutLinter = `#!/bin/sh
+gopath="${GOPATH%%:*}"
+for arg in "$@"; do
+	if [ -d "$arg" ]; then
+		for f in "$arg"/*.go; do
+			echo "$f:1:0:warning: ${arg#$gopath/src/} GO111MODULE=$GO111MODULE GOFLAGS=[$GOFLAGS] (fake)"
+		done
+	fi
+done
+`
+
+func TestReviewLayout(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, layoutLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("pkg/b.go", "package pkg\n")},
+	)
+	client.Files = []*pb.File{datatest.File("go.mod", "module example.com/lookout\n")}
+
+	e := &pb.ReviewEvent{}
+	e.Head.InternalRepositoryURL = "https://github.com/src-d/lookout"
+
+	a := &Analyzer{DataClient: client}
+	resp, err := a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal([]*pb.Comment{
+		{File: "a.go", Line: 1, Text: " example.com/lookout GO111MODULE=off GOFLAGS=[] (fake)"},
+		{File: "pkg/b.go", Line: 1, Text: " example.com/lookout/pkg GO111MODULE=off GOFLAGS=[] (fake)"},
+	}, resp.Comments)
+
+	client.Files = nil
+	resp, err = a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal(" github.com/src-d/lookout GO111MODULE=off GOFLAGS=[] (fake)",
+		resp.Comments[0].Text)
+}
+
+// localPrefixLinter reports the local prefix passed to goimports for every file
+const localPrefixLinter = `#!/bin/sh
+prefix=none
+for arg in "$@"; do
+	case "$arg" in
+	--linter=goimports:*) prefix="${arg#*-local }"; prefix="${prefix%%:*}";;
+	esac
+done
+for arg in "$@"; do
+	if [ -d "$arg" ]; then
+		for f in "$arg"/*.go; do
+			echo "$f:1:0:warning: local $prefix (fake)"
+		done
+	fi
+done
+`
+
+func TestReviewLocalPrefix(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, localPrefixLinter)()
+
+	client := datatest.NewDataClient(&pb.Change{Head: datatest.File("a.go", "package a\n")})
+	client.Files = []*pb.File{datatest.File("go.mod", "module example.com/lookout\n")}
+
+	e := &pb.ReviewEvent{}
+	a := &Analyzer{DataClient: client}
+	resp, err := a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal(" local example.com/lookout (fake)", resp.Comments[0].Text,
+		"the import path must be the default local prefix")
+
+	e.Configuration = *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{{"name": "goimports", "localPrefix": "example.com"}},
+	})
+	resp, err = a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal(" local example.com (fake)", resp.Comments[0].Text)
+
+	e.Configuration = *pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{{"name": "goimports", "localPrefix": ""}},
+	})
+	resp, err = a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal(" local none (fake)", resp.Comments[0].Text,
+		"empty prefix must disable the default")
+
+	client.Files = nil
+	e.Configuration = types.Struct{}
+	resp, err = a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal(" local none (fake)", resp.Comments[0].Text,
+		"repositories with unknown import path must have no local prefix")
+}
+
+func TestReviewConfigErrors(t *testing.T) {
+	defer withFakeLinter(t, fakeLinter)()
+
+	client := datatest.NewDataClient(&pb.Change{Head: datatest.File("a.go", "package a\n")})
+	require.Equal(t, []*pb.Comment{
+		{Text: "The analyzer configuration has errors, the wrong options are ignored:\n" +
+			"- `linters[0].maxLen`: must be an integer"},
+		{File: "a.go", Line: 1, Text: "issue in a.go (fake)"},
+	}, review(t, client, map[string]interface{}{
+		"linters": []map[string]interface{}{{"name": "lll", "maxLen": "long"}},
+	}))
+}
+
+// customLinterScript prints issues in JSON with paths relative to --dir
+const customLinterScript = `#!/bin/sh
+for arg in "$@"; do
+	case "$arg" in
+	--dir=*) dir="${arg#--dir=}";;
+	--strict) strict=" strictly";;
+	esac
+done
+for f in "$dir"/*.go; do
+	echo "{\"file\": \"$(basename "$f")\", \"line\": 2, \"text\": \"custom issue$strict\"}"
+done
+`
+
+func TestReviewCustomLinters(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, argsLinter)()
+
+	dir, err := ioutil.TempDir("", "gometalint-custom")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "customlint")
+	require.NoError(ioutil.WriteFile(script, []byte(customLinterScript), 0755))
+
+	defer withCustomLinters(t, CustomLinter{
+		Name:     "customlint",
+		Command:  script + " {args} --dir={path}",
+		JSON:     &JSONMapping{Path: "file", Line: "line", Message: "text"},
+		Severity: "error",
+		Options: map[string]CustomOption{
+			"strict": {Type: "bool", Argument: "--strict"},
+		},
+	})()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("pkg/b.go", "package pkg\n")},
+	)
+
+	require.Equal([]*pb.Comment{
+		{File: "a.go", Line: 1, Text: " args (fake)"},
+		{File: "a.go", Line: 2, Text: " custom issue (customlint)"},
+		{File: "pkg/b.go", Line: 1, Text: " args --line-length=100 (fake)"},
+		{File: "pkg/b.go", Line: 2, Text: " custom issue strictly (customlint)"},
+	}, review(t, client, map[string]interface{}{
+		"overrides": []map[string]interface{}{
+			{"paths": []string{"pkg"}, "linters": []map[string]interface{}{
+				{"name": "customlint", "strict": true},
+				{"name": "lll", "maxLen": 100},
+			}},
+		},
+	}))
+}
+
+const repeatingLinter = `#!/bin/sh
+for arg in "$@"; do
+	if [ -d "$arg" ]; then
+		for f in "$arg"/*.go; do
+			for line in 3 1 2; do
+				echo "$f:$line:2:warning: long line (lll)"
+			done
+		done
+	fi
+done
+`
+
+func TestReviewAggregation(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, repeatingLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("b.go", "package b\n")},
+	)
+
+	require.Equal([]*pb.Comment{
+		{Text: "Some issues are not posted as separate comments:\n" +
+			"- 3 issues of lll in `a.go` are collapsed into one comment\n" +
+			"- 3 issues of lll in `b.go` are collapsed into one comment\n" +
+			"- 1 issues over the limit of 1 comments per review"},
+		{File: "a.go", Line: 1, Text: " 3 issues on lines 1, 2, 3: long line (lll)"},
+	}, review(t, client, map[string]interface{}{
+		"aggregate":   true,
+		"maxComments": 1,
+	}))
+
+	require.Len(review(t, client, nil), 6, "comments must not be aggregated by default")
+}
+
+func TestReviewFingerprints(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, repeatingLinter)()
+
+	client := datatest.NewDataClient(&pb.Change{Head: datatest.File("a.go", "package a\n")})
+	issues := []Comment{
+		NewComment("warning", "a.go", 1, 2, " long line (lll)"),
+		NewComment("warning", "a.go", 2, 2, " long line (lll)"),
+		NewComment("warning", "a.go", 3, 2, " long line (lll)"),
+	}
+	expected := Fingerprints(issues)
+	require.Len(expected, 3)
+	require.NotEqual(expected[0], expected[1], "the same issues must have different fingerprints")
+
+	for _, format := range []string{"", "raw", "markdown", "plain"} {
+		e := &pb.ReviewEvent{}
+		if format != "" {
+			e.Configuration = *pb.ToStruct(map[string]interface{}{"commentFormat": format})
+		}
+
+		a := &Analyzer{DataClient: client}
+		resp, err := a.NotifyReviewEvent(context.Background(), e)
+		require.NoError(err)
+		require.Len(resp.Comments, 3)
+
+		for i, c := range resp.Comments {
+			if format != "markdown" {
+				require.NotContains(c.Text, "fingerprint", "format %q: only markdown has fingerprints", format)
+				continue
+			}
+
+			m := fingerprintSuffix.FindStringSubmatch(c.Text)
+			require.NotNil(m, "comment must have fingerprint: %s", c.Text)
+			require.Equal(expected[i], m[1])
+		}
+	}
+}
+
+func TestReviewSummary(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, fakeLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{
+			Base: datatest.File("a.go", "package a\n"),
+			Head: datatest.File("a.go", "package a\n\nvar a = 1\n"),
+		},
+		&pb.Change{Head: datatest.File("b.go", "package b\n")},
+		&pb.Change{Head: datatest.File("b.pb.go", "package b\n")},
+	)
+
+	comments := review(t, client, map[string]interface{}{"summary": true})
+	require.Len(comments, 3)
+	require.Regexp("^gometalint summary:\n"+
+		"- files: 2 analyzed, 1 skipped \\(generated: 1\\)\n"+
+		"- issues: 1 new, 1 pre-existing\n"+
+		"  - `fake`: 2 warning\n"+
+		"- lint time: .+s$", comments[0].Text)
+	require.Equal("", comments[0].File)
+
+	require.Len(review(t, client, nil), 2, "summary must be disabled by default")
+}
+
+// badLinter reports every line containing BAD with its number in the message
+const badLinter = `#!/bin/sh
+for arg in "$@"; do
+	if [ -d "$arg" ]; then
+		for f in "$arg"/*.go; do
+			grep -n BAD "$f" | while IFS=: read line rest; do
+				echo "$f:$line:1:warning: bad line $line (lll)"
+			done
+		done
+	fi
+done
+`
+
+func TestReviewNewIssuesOnly(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, badLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{
+			Base: datatest.File("a.go", "package a\n// BAD one\nvar y = 1 // BAD\n"),
+			Head: datatest.File("a.go", "package a\n\n// BAD one\nvar y = 2 // BAD\nvar z = 3 // BAD\n"),
+		},
+		&pb.Change{
+			Base: datatest.File("b.go", "package b\n// BAD\n"),
+			Head: datatest.File("b.go", "package b\n"),
+		},
+		&pb.Change{Head: datatest.File("c.go", "package c // BAD\n")},
+	)
+
+	require.Equal([]*pb.Comment{
+		{Text: "1 issues are fixed, thank you!"},
+		{File: "a.go", Line: 5, Text: " bad line 5 (lll)"},
+		{File: "c.go", Line: 1, Text: " bad line 1 (lll)"},
+	}, review(t, client, map[string]interface{}{
+		"newIssuesOnly": true,
+		"praiseFixed":   true,
+	}))
+
+	comments := review(t, client, map[string]interface{}{
+		"newIssuesOnly": true,
+		"praiseFixed":   true,
+		"summary":       true,
+	})
+	require.Len(comments, 3)
+	require.Contains(comments[0].Text, "- issues: 2 new, 2 pre-existing\n")
+	require.Contains(comments[0].Text, "- 1 issues are fixed, thank you!\n")
+
+	require.Len(review(t, client, map[string]interface{}{"aggregate": false}), 4)
+}
+
+func TestReviewCommentFormat(t *testing.T) {
+	defer withFakeLinter(t, fakeLinter)()
+
+	client := datatest.NewDataClient(&pb.Change{Head: datatest.File("a.go", "package a\n")})
+	require.Equal(t, []*pb.Comment{{
+		File: "a.go",
+		Line: 1,
+		Text: "[fake] issue in a.go\nTo suppress, add \"// nolint: fake\" at the end of the line.",
+	}}, review(t, client, map[string]interface{}{"commentFormat": "plain"}))
+}
+
+func TestReviewCommentFormatReset(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, fakeLinter)()
+
+	client := datatest.NewDataClient(&pb.Change{Head: datatest.File("a.go", "package a\n")})
+	profile := &Profile{Repositories: []RepositoryProfile{{
+		Repos:    []string{"src-d/*"},
+		Settings: *pb.ToStruct(map[string]interface{}{"commentFormat": "markdown"}),
+	}}}
+	a := &Analyzer{DataClient: client, Profile: NewProfileStore(profile)}
+
+	e := &pb.ReviewEvent{}
+	e.Head.InternalRepositoryURL = "https://github.com/src-d/lookout"
+	resp, err := a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Len(resp.Comments, 1)
+	require.Regexp(fingerprintSuffix, resp.Comments[0].Text, "markdown of the profile must be used")
+
+	e.Configuration = *pb.ToStruct(map[string]interface{}{"commentFormat": "raw"})
+	resp, err = a.NotifyReviewEvent(context.Background(), e)
+	require.NoError(err)
+	require.Equal([]*pb.Comment{{File: "a.go", Line: 1, Text: "issue in a.go (fake)"}}, resp.Comments,
+		"repository must be able to reset the format of the profile")
+}
+
+func TestReviewPartial(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, fakeLinter)()
+	defer withFastRetries()()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Head: datatest.File("a.go", "package a\n")},
+		&pb.Change{Head: datatest.File("b.go", "package b\n")},
+	)
+	client.ChangesFailures = []datatest.Failure{
+		{After: 1, Err: status.Error(codes.Unavailable, "unavailable")},
+		{After: 1, Err: status.Error(codes.Internal, "broken")},
+	}
+
+	require.Equal([]*pb.Comment{
+		{Text: "The analysis is partial, not all changed files were received from DataService: broken"},
+		{File: "a.go", Line: 1, Text: "issue in a.go (fake)"},
+	}, review(t, client, nil))
+	require.Len(client.ChangesRequests(), 2)
+}
+
+const unsortedLinter = `#!/bin/sh
+for arg in "$@"; do
+	if [ -d "$arg" ]; then
+		for f in "$arg"/*.go; do
+			echo "$f:2:1:warning: long line (lll)"
+			echo "$f:0::warning: file is not gofmted with -s (gofmt)"
+			echo "$f:2:1:warning: long line (lll)"
+			echo "$f:0::warning: file is not goimported (goimports)"
+			echo "$f:1:1:warning: unsafe (gosec)"
+		done
+	fi
+done
+`
+
+func TestReviewSorted(t *testing.T) {
+	defer withFakeLinter(t, unsortedLinter)()
+
+	client := datatest.NewDataClient(&pb.Change{Head: datatest.File("a.go", "package a\n")})
+	require.Equal(t, []*pb.Comment{
+		{File: "a.go", Line: 0, Text: " file is not goimported (goimports)"},
+		{File: "a.go", Line: 1, Text: " unsafe (gosec)"},
+		{File: "a.go", Line: 2, Text: " long line (lll)"},
+	}, review(t, client, nil))
+}
+
+func TestReviewNoGoFiles(t *testing.T) {
+	defer withFakeLinter(t, fakeLinter)()
+
+	client := datatest.NewDataClient(
+		&pb.Change{Base: datatest.File("deleted.go", "package main\n")},
+		&pb.Change{Head: datatest.File("README.md", "# readme\n")},
+	)
+	require.Empty(t, review(t, client, nil))
+}
+
+var fixtureTests = []struct {
+	fixture  string
+	comments []*pb.Comment
+}{
+	{"lll_test.go", []*pb.Comment{
+		{File: "lll_test.go", Line: 8, Text: " line is 120 characters (lll)"},
+		{File: "lll_test.go", Line: 11, Text: " line is 108 characters (lll)"},
+	}},
+	{"misspell_test.go", []*pb.Comment{
+		{Text: "Some issues are not posted as separate comments:\n" +
+			"- 4 issues of misspell in `misspell_test.go` are collapsed into one comment"},
+		{File: "misspell_test.go", Line: 8, Text: ` 4 issues on lines 8, 9, 12, 13: "langauge" is a misspelling of "language" (misspell)`},
+		{File: "misspell_test.go", Line: 12, Text: " line is 136 characters (lll)"},
+		{File: "misspell_test.go", Line: 13, Text: " line is 136 characters (lll)"},
+	}},
+}
+
+func TestReviewFixtures(t *testing.T) {
+	if _, err := exec.LookPath(bin); err != nil {
+		t.Skipf("%s is not installed", bin)
+	}
+
+	for _, tt := range fixtureTests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			client := datatest.NewDataClient(fixtureChanges(t, tt.fixture)...)
+			assert.ElementsMatch(t, tt.comments, review(t, client, nil))
+		})
+	}
 }
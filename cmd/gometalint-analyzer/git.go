@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/src-d/lookout-gometalint-analyzer/localdata"
+
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// gitRepo reads revisions of a git repository on disk using git binary
+type gitRepo struct {
+	path string
+}
+
+// newGitRepo returns the repository at the path,
+// it fails if git binary is not available
+func newGitRepo(path string) (gitRepo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return gitRepo{}, fmt.Errorf("git not found in PATH, it's required to read the repository")
+	}
+
+	return gitRepo{path: path}, nil
+}
+
+type treeEntry struct {
+	mode uint32
+	hash string
+}
+
+func (r gitRepo) git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.path}, args...)...) // nolint: gas
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %s: %s",
+			strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// resolve returns hash of the commit referenced by rev
+func (r gitRepo) resolve(rev string) (string, error) {
+	out, err := r.git("rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// tree returns all blobs in the commit indexed by path
+func (r gitRepo) tree(hash string) (map[string]treeEntry, error) {
+	return r.lsTree("-r", "-z", "--full-tree", hash)
+}
+
+// rootFiles returns files in the root directory of the commit
+func (r gitRepo) rootFiles(hash string) ([]*pb.File, error) {
+	entries, err := r.lsTree("-z", "--full-tree", hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for filePath := range entries {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+
+	var files []*pb.File
+	for _, filePath := range paths {
+		file, err := r.file(filePath, entries[filePath])
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// lsTree returns blobs listed by ls-tree with the arguments indexed by path
+func (r gitRepo) lsTree(args ...string) (map[string]treeEntry, error) {
+	out, err := r.git(append([]string{"ls-tree"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]treeEntry)
+	for _, line := range strings.Split(string(out), "\x00") {
+		if line == "" {
+			continue
+		}
+
+		// <mode> SP <type> SP <object> TAB <file>
+		tab := strings.IndexByte(line, '\t')
+		if tab < 0 {
+			return nil, fmt.Errorf("unexpected ls-tree output %q", line)
+		}
+
+		fields := strings.Fields(line[:tab])
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected ls-tree output %q", line)
+		}
+
+		if fields[1] != "blob" {
+			continue
+		}
+
+		mode, err := strconv.ParseUint(fields[0], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("wrong mode in ls-tree output %q", line)
+		}
+
+		entries[line[tab+1:]] = treeEntry{mode: uint32(mode), hash: fields[2]}
+	}
+
+	return entries, nil
+}
+
+func (r gitRepo) file(filePath string, entry treeEntry) (*pb.File, error) {
+	content, err := r.git("cat-file", "blob", entry.hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.File{
+		Path:     filePath,
+		Mode:     entry.mode,
+		Hash:     entry.hash,
+		Content:  content,
+		Language: localdata.LanguageByPath(filePath),
+	}, nil
+}
+
+// changes returns files changed between two commits
+func (r gitRepo) changes(from, to string) ([]*pb.Change, error) {
+	out, err := r.git("diff", "--name-only", "-z", "--no-renames", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	baseTree, err := r.tree(from)
+	if err != nil {
+		return nil, err
+	}
+
+	headTree, err := r.tree(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*pb.Change
+	for _, filePath := range strings.Split(string(out), "\x00") {
+		if filePath == "" {
+			continue
+		}
+
+		change := &pb.Change{}
+		if entry, ok := baseTree[filePath]; ok {
+			if change.Base, err = r.file(filePath, entry); err != nil {
+				return nil, err
+			}
+		}
+
+		if entry, ok := headTree[filePath]; ok {
+			if change.Head, err = r.file(filePath, entry); err != nil {
+				return nil, err
+			}
+		}
+
+		if change.Base == nil && change.Head == nil {
+			// not a blob, e.g. submodule
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
@@ -5,6 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	gometalint "github.com/src-d/lookout-gometalint-analyzer"
 
@@ -16,10 +19,12 @@ import (
 )
 
 var usageMessage = fmt.Sprintf(`usage: %s [-version] [OPTIONS]
+       %s review [--repo PATH] [--from REV] [--to REV] [-- GOMETALINTER_ARGS]
+       %s validate-config [--analyzer NAME] [PATH]
 
 %s is a lookout analyzer implementation, based on https://github.com/alecthomas/gometalinter.
 
-`, name, name)
+`, name, name, name, name)
 
 var (
 	name        = "gometalint-analyzer"
@@ -33,14 +38,123 @@ type config struct {
 	Port           int    `envconfig:"PORT" default:"9930"`
 	DataServiceURL string `envconfig:"DATA_SERVICE_URL" default:"ipv4://localhost:10301"`
 	LogLevel       string `envconfig:"LOG_LEVEL" default:"info" description:"Logging level (info, debug, warning or error)"`
+
+	TLSCert     string `envconfig:"TLS_CERT" description:"Path to the PEM server certificate, enables TLS"`
+	TLSKey      string `envconfig:"TLS_KEY" description:"Path to the PEM server private key"`
+	TLSClientCA string `envconfig:"TLS_CLIENT_CA" description:"Path to the PEM CA bundle to verify client certificates, enables mutual TLS"`
+
+	DataServiceTLS           bool   `envconfig:"DATA_SERVICE_TLS" description:"Use TLS to connect to DataService"`
+	DataServiceTLSCA         string `envconfig:"DATA_SERVICE_TLS_CA" description:"Path to the PEM CA bundle to verify DataService certificate"`
+	DataServiceTLSCert       string `envconfig:"DATA_SERVICE_TLS_CERT" description:"Path to the PEM client certificate for DataService"`
+	DataServiceTLSKey        string `envconfig:"DATA_SERVICE_TLS_KEY" description:"Path to the PEM client private key for DataService"`
+	DataServiceTLSServerName string `envconfig:"DATA_SERVICE_TLS_SERVER_NAME" description:"Override of the DataService server name used to verify its certificate"`
+
+	MaxFileSize  int64 `envconfig:"MAX_FILE_SIZE" default:"1048576" description:"Maximum size of an analyzed file in bytes, 0 for no limit"`
+	MaxTotalSize int64 `envconfig:"MAX_TOTAL_SIZE" default:"52428800" description:"Maximum size of all files analyzed in one review in bytes, 0 for no limit"`
+	MaxFiles     int   `envconfig:"MAX_FILES" default:"1000" description:"Maximum number of files analyzed in one review, 0 for no limit"`
+	Concurrency  int   `envconfig:"CONCURRENCY" default:"0" description:"Number of lint jobs running at the same time, 0 for the number of CPUs"`
+
+	LintersConfig string `envconfig:"LINTERS_CONFIG" description:"Path to YAML or JSON file with definitions of custom linters"`
+
+	Profile               string        `envconfig:"PROFILE" description:"Path to YAML or JSON file with the server-wide profile of linters and limits"`
+	ProfileReloadInterval time.Duration `envconfig:"PROFILE_RELOAD_INTERVAL" default:"10s" description:"Interval of checks of the profile file for changes, 0 to reload it only on SIGHUP"`
+
+	Sandbox          bool          `envconfig:"SANDBOX" default:"false" description:"Run linters in a restricted environment, Linux only"`
+	SandboxCPUTime   time.Duration `envconfig:"SANDBOX_CPU_TIME" default:"5m" description:"CPU time limit of every linter process, 0 for no limit"`
+	SandboxMemory    uint64        `envconfig:"SANDBOX_MEMORY" default:"4294967296" description:"Address space limit of every linter process in bytes, 0 for no limit"`
+	SandboxOpenFiles uint64        `envconfig:"SANDBOX_OPEN_FILES" default:"1024" description:"Open files limit of every linter process, 0 for no limit"`
+	SandboxEnv       []string      `envconfig:"SANDBOX_ENV" default:"PATH,GOPATH,GOROOT,LANG,LC_ALL" description:"Environment variables passed to linters"`
+	SandboxNetwork   bool          `envconfig:"SANDBOX_NETWORK" default:"false" description:"Allow network access to linters"`
+	SandboxReadOnly  bool          `envconfig:"SANDBOX_READ_ONLY" default:"true" description:"Make file system read-only for linters except for analyzed files"`
+}
+
+// limits returns limits of files analyzed in one review
+func (c config) limits() gometalint.Limits {
+	return gometalint.Limits{
+		MaxFileSize:  c.MaxFileSize,
+		MaxTotalSize: c.MaxTotalSize,
+		MaxFiles:     c.MaxFiles,
+	}
+}
+
+// sandbox returns linters sandbox configuration, nil if it's disabled
+func (c config) sandbox() *gometalint.Sandbox {
+	if !c.Sandbox {
+		return nil
+	}
+
+	return &gometalint.Sandbox{
+		CPUTime:   c.SandboxCPUTime,
+		Memory:    c.SandboxMemory,
+		OpenFiles: c.SandboxOpenFiles,
+		Env:       c.SandboxEnv,
+		NoNetwork: !c.SandboxNetwork,
+		ReadOnly:  c.SandboxReadOnly,
+	}
+}
+
+// loadProfile reads the profile file, the options missing in it and
+// the profile without the file are taken from the environment
+func (c config) loadProfile() (*gometalint.Profile, error) {
+	defaults := gometalint.Profile{Limits: c.limits(), Concurrency: c.Concurrency}
+	if c.Profile == "" {
+		return &defaults, nil
+	}
+
+	return gometalint.LoadProfile(c.Profile, defaults)
+}
+
+// loadLinters sets custom linters defined in the linters configuration file
+func (c config) loadLinters() error {
+	if c.LintersConfig == "" {
+		return nil
+	}
+
+	linters, err := gometalint.LoadCustomLinters(c.LintersConfig)
+	if err != nil {
+		return err
+	}
+
+	return gometalint.SetCustomLinters(linters)
 }
 
 func main() {
+	gometalint.SandboxMain()
+
 	litter.Config.Compact = true
 	flag.Usage = func() {
-		fmt.Printf(usageMessage)
+		fmt.Print(usageMessage)
 		flag.PrintDefaults()
 	}
+
+	if len(os.Args) > 1 && os.Args[1] == "review" {
+		var conf config
+		envconfig.MustProcess("GOMETALINT", &conf)
+		log.DefaultFactory = &log.LoggerFactory{Level: conf.LogLevel}
+		log.DefaultLogger = log.New(nil)
+
+		if err := runReview(os.Args[2:], conf, os.Stdout); err != nil {
+			log.Errorf(err, "review failed")
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		var conf config
+		envconfig.MustProcess("GOMETALINT", &conf)
+		if err := conf.loadLinters(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if err := runValidateConfig(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	flag.Parse()
 
 	if *versionFlag {
@@ -54,6 +168,23 @@ func main() {
 	log.DefaultFactory = &log.LoggerFactory{Level: conf.LogLevel}
 	log.DefaultLogger = log.New(nil)
 
+	if err := conf.loadLinters(); err != nil {
+		log.Errorf(err, "failed to load custom linters from %s", conf.LintersConfig)
+		return
+	}
+
+	profiles, err := newProfileWatcher(conf)
+	if err != nil {
+		log.Errorf(err, "failed to load profile from %s", conf.Profile)
+		return
+	}
+
+	if conf.Profile != "" {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGHUP)
+		go profiles.run(conf.ProfileReloadInterval, signals, nil)
+	}
+
 	grpcAddr, err := pb.ToGoGrpcAddress(conf.DataServiceURL)
 	if err != nil {
 		log.Errorf(err, "failed to parse DataService addres %s", conf.DataServiceURL)
@@ -64,9 +195,16 @@ func main() {
 		log.With(log.Fields(fields)).Debugf(format, args...)
 	}
 
-	conn, err := pb.DialContextWithInterceptors(
+	clientCreds, err := dataServiceCredentials(conf)
+	if err != nil {
+		log.Errorf(err, "failed to configure TLS for DataService")
+		return
+	}
+
+	conn, err := dialContext(
 		context.Background(),
 		grpcAddr,
+		clientCreds,
 		[]grpc.StreamClientInterceptor{
 			pb.LogStreamClientInterceptor(logFn),
 		},
@@ -83,7 +221,19 @@ func main() {
 	analyzer := &gometalint.Analyzer{
 		Version:    version,
 		DataClient: pb.NewDataClient(conn),
-		Args:       append([]string(nil), os.Args[1:]...),
+		Sandbox:    conf.sandbox(),
+		Profile:    profiles.store,
+	}
+
+	serverCreds, err := serverCredentials(conf)
+	if err != nil {
+		log.Errorf(err, "failed to configure TLS for gRPC server")
+		return
+	}
+
+	var serverOpts []grpc.ServerOption
+	if serverCreds != nil {
+		serverOpts = append(serverOpts, grpc.Creds(serverCreds))
 	}
 
 	server := pb.NewServerWithInterceptors(
@@ -93,6 +243,7 @@ func main() {
 		[]grpc.UnaryServerInterceptor{
 			pb.LogUnaryServerInterceptor(logFn),
 		},
+		serverOpts...,
 	)
 	pb.RegisterAnalyzerServer(server, analyzer)
 
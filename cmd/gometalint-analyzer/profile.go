@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
+
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// profileWatcher reloads the profile file when it changes or on a signal
+type profileWatcher struct {
+	conf  config
+	store *gometalint.ProfileStore
+	// modTime and size of the file when it was read last time
+	modTime time.Time
+	size    int64
+}
+
+// newProfileWatcher loads the profile of the configuration,
+// error is returned if the profile file is wrong
+func newProfileWatcher(conf config) (*profileWatcher, error) {
+	w := &profileWatcher{conf: conf}
+	w.changed()
+
+	p, err := conf.loadProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	w.store = gometalint.NewProfileStore(p)
+	return w, nil
+}
+
+// reload reads the profile file again, the current profile is kept
+// if the file is wrong
+func (w *profileWatcher) reload() error {
+	p, err := w.conf.loadProfile()
+	if err != nil {
+		return err
+	}
+
+	w.store.Set(p)
+	return nil
+}
+
+// changed returns true if the file was modified since the previous call
+func (w *profileWatcher) changed() bool {
+	fi, err := os.Stat(w.conf.Profile)
+	if err != nil {
+		return false
+	}
+
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return false
+	}
+
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	return true
+}
+
+// run reloads the profile when the file changes, it's checked every interval,
+// or a signal is received until done is closed. The file isn't checked if
+// the interval isn't positive. Reviews that are running keep using the profile
+// they were started with.
+func (w *profileWatcher) run(interval time.Duration, signals <-chan os.Signal, done <-chan struct{}) {
+	// nil channel never ticks
+	var ticks <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case sig := <-signals:
+			w.changed()
+			log.Infof("reloading profile %s on %s", w.conf.Profile, sig)
+		case <-ticks:
+			if !w.changed() {
+				continue
+			}
+
+			log.Infof("reloading changed profile %s", w.conf.Profile)
+		}
+
+		if err := w.reload(); err != nil {
+			log.Errorf(err, "failed to reload profile, the previous one is used")
+		}
+	}
+}
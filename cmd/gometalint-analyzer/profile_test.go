@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileWatcher(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-profile")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "profile.yml")
+	require.NoError(ioutil.WriteFile(path, []byte("maxFiles: 10\n"), 0644))
+
+	conf := config{Profile: path, MaxFileSize: 1024, Concurrency: 2}
+	w, err := newProfileWatcher(conf)
+	require.NoError(err)
+	require.Equal(&gometalint.Profile{
+		Limits:      gometalint.Limits{MaxFileSize: 1024, MaxFiles: 10},
+		Concurrency: 2,
+	}, w.store.Get())
+	require.False(w.changed())
+
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		w.run(time.Millisecond, signals, done)
+		close(stopped)
+	}()
+
+	require.NoError(ioutil.WriteFile(path, []byte("maxFiles: 20\nconcurrency: 4\n"), 0644))
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		p := w.store.Get()
+		if p.Limits.MaxFiles == 20 && p.Concurrency == 4 {
+			break
+		}
+
+		require.True(time.Now().Before(deadline), "changed profile must be reloaded")
+	}
+
+	previous := w.store.Get()
+	require.NoError(ioutil.WriteFile(path, []byte("maxFiles: many\n"), 0644))
+	signals <- syscall.SIGHUP
+	signals <- syscall.SIGHUP
+	require.Equal(previous, w.store.Get(), "wrong profile must not replace the current one")
+
+	close(done)
+	<-stopped
+
+	require.NoError(ioutil.WriteFile(path, []byte("maxFiles: 30\n"), 0644))
+	require.NoError(w.reload())
+	require.Equal(30, w.store.Get().Limits.MaxFiles)
+	require.Equal(20, previous.Limits.MaxFiles, "profiles in use must not be changed")
+
+	_, err = newProfileWatcher(config{Profile: filepath.Join(dir, "missing.yml")})
+	require.Error(err)
+
+	w, err = newProfileWatcher(config{MaxFiles: 5})
+	require.NoError(err)
+	require.Equal(5, w.store.Get().Limits.MaxFiles)
+}
+
+func TestProfileWatcherNoInterval(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-profile")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "profile.yml")
+	require.NoError(ioutil.WriteFile(path, []byte("maxFiles: 10\n"), 0644))
+
+	w, err := newProfileWatcher(config{Profile: path})
+	require.NoError(err)
+
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		w.run(0, signals, done)
+		close(stopped)
+	}()
+
+	require.NoError(ioutil.WriteFile(path, []byte("maxFiles: 20\n"), 0644))
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(10, w.store.Get().Limits.MaxFiles, "file must not be checked without interval")
+
+	signals <- syscall.SIGHUP
+	close(done)
+	<-stopped
+	require.Equal(20, w.store.Get().Limits.MaxFiles, "profile must be reloaded on signal")
+}
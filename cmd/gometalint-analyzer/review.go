@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
+	"github.com/src-d/lookout-gometalint-analyzer/localdata"
+
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+var reviewUsageMessage = fmt.Sprintf(`usage: %s review [--repo PATH] [--from REV] [--to REV] [-- GOMETALINTER_ARGS]
+
+Runs the analyzer on changes between two revisions of a local git repository
+and prints the resulting comments.
+
+`, name)
+
+// runReview analyzes changes between two commits of a local repository
+// the same way NotifyReviewEvent does for lookout and prints comments to out.
+// Only options of conf related to linters, the profile and limits are used.
+func runReview(args []string, conf config, out io.Writer) error {
+	flags := flag.NewFlagSet("review", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), reviewUsageMessage)
+		flags.PrintDefaults()
+	}
+
+	repoPath := flags.String("repo", ".", "path to the git repository")
+	from := flags.String("from", "HEAD^", "base revision")
+	to := flags.String("to", "HEAD", "head revision")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := conf.loadLinters(); err != nil {
+		return err
+	}
+
+	profile, err := conf.loadProfile()
+	if err != nil {
+		return err
+	}
+
+	absPath, err := filepath.Abs(*repoPath)
+	if err != nil {
+		return err
+	}
+
+	repo, err := newGitRepo(absPath)
+	if err != nil {
+		return err
+	}
+
+	base, err := repo.resolve(*from)
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.resolve(*to)
+	if err != nil {
+		return err
+	}
+
+	changes, err := repo.changes(base, head)
+	if err != nil {
+		return err
+	}
+
+	// files of the root directory are served for repository configuration
+	files, err := repo.rootFiles(head)
+	if err != nil {
+		return err
+	}
+
+	client := localdata.NewDataClient(changes...)
+	client.Files = files
+
+	analyzer := &gometalint.Analyzer{
+		Version:    version,
+		DataClient: client,
+		Args:       flags.Args(),
+		Sandbox:    conf.sandbox(),
+		Profile:    gometalint.NewProfileStore(profile),
+	}
+
+	repoURL := "file://" + filepath.ToSlash(absPath)
+	resp, err := analyzer.NotifyReviewEvent(context.Background(), &pb.ReviewEvent{
+		Provider: "local",
+		CommitRevision: pb.CommitRevision{
+			Base: pb.ReferencePointer{InternalRepositoryURL: repoURL, Hash: base},
+			Head: pb.ReferencePointer{InternalRepositoryURL: repoURL, Hash: head},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range resp.Comments {
+		printComment(out, c)
+	}
+
+	return nil
+}
+
+func printComment(out io.Writer, c *pb.Comment) {
+	switch {
+	case c.File == "":
+		fmt.Fprintln(out, c.Text)
+	case c.Line == 0:
+		fmt.Fprintf(out, "%s: %s\n", c.File, c.Text)
+	default:
+		fmt.Fprintf(out, "%s:%d: %s\n", c.File, c.Line, c.Text)
+	}
+}
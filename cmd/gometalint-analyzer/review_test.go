@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// newTestRepo creates a git repository with two commits and returns
+// its path and hashes of the commits
+func newTestRepo(t *testing.T) (string, string, string) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-repo")
+	require.NoError(err)
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir,
+			"-c", "user.name=test", "-c", "user.email=test@example.com",
+			"-c", "commit.gpgsign=false"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	write := func(name, content string) {
+		p := filepath.Join(dir, name)
+		require.NoError(os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(ioutil.WriteFile(p, []byte(content), 0644))
+	}
+
+	git("init", "-q")
+	write("main.go", "package main\n")
+	write("deleted.go", "package main\n")
+	write("unchanged.go", "package main\n")
+	write("README.md", "# readme\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "first")
+	base := git("rev-parse", "HEAD")
+
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("pkg/added.go", "package pkg\n")
+	write("vendor/dep/dep.go", "package dep\n")
+	write("README.md", "# changed readme\n")
+	require.NoError(os.Remove(filepath.Join(dir, "deleted.go")))
+	git("add", "-A")
+	git("commit", "-q", "-m", "second")
+	head := git("rev-parse", "HEAD")
+
+	return dir, base, head
+}
+
+func TestGitNotFound(t *testing.T) {
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	os.Setenv("PATH", "")
+
+	_, err := newGitRepo(".")
+	require.EqualError(t, err, "git not found in PATH, it's required to read the repository")
+}
+
+func changedPaths(changes []*pb.Change) []string {
+	var paths []string
+	for _, change := range changes {
+		f := change.Head
+		if f == nil {
+			f = change.Base
+		}
+
+		paths = append(paths, f.Path)
+	}
+
+	return paths
+}
+
+func TestGitChanges(t *testing.T) {
+	require := require.New(t)
+	dir, base, head := newTestRepo(t)
+	defer os.RemoveAll(dir)
+
+	repo, err := newGitRepo(dir)
+	require.NoError(err)
+	resolved, err := repo.resolve("HEAD^")
+	require.NoError(err)
+	require.Equal(base, resolved)
+
+	_, err = repo.resolve("no-such-revision")
+	require.Error(err)
+
+	changes, err := repo.changes(base, head)
+	require.NoError(err)
+	require.Equal([]string{
+		"README.md", "deleted.go", "main.go", "pkg/added.go", "vendor/dep/dep.go",
+	}, changedPaths(changes))
+
+	byPath := make(map[string]*pb.Change)
+	for _, change := range changes {
+		byPath[changedPaths([]*pb.Change{change})[0]] = change
+	}
+
+	require.Nil(byPath["deleted.go"].Head)
+	require.Nil(byPath["pkg/added.go"].Base)
+	require.Equal("package main\n", string(byPath["main.go"].Base.Content))
+	require.Equal("package main\n\nfunc main() {}\n", string(byPath["main.go"].Head.Content))
+	require.Equal("Go", byPath["main.go"].Head.Language)
+	require.Equal(uint32(0100644), byPath["main.go"].Head.Mode)
+
+	files, err := repo.rootFiles(head)
+	require.NoError(err)
+	require.Len(files, 3)
+	require.Equal([]string{"README.md", "main.go", "unchanged.go"},
+		changedPaths([]*pb.Change{{Head: files[0]}, {Head: files[1]}, {Head: files[2]}}))
+	require.Equal("# changed readme\n", string(files[0].Content))
+}
+
+func TestReview(t *testing.T) {
+	require := require.New(t)
+	dir, _, _ := newTestRepo(t)
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	require.NoError(runReview([]string{"--repo", dir}, config{}, &out))
+	require.Error(runReview([]string{"--repo", dir, "--from", "no-such-revision"}, config{}, &out))
+}
+
+func TestPrintComment(t *testing.T) {
+	var out bytes.Buffer
+	printComment(&out, &pb.Comment{Text: "global"})
+	printComment(&out, &pb.Comment{File: "a.go", Text: "file"})
+	printComment(&out, &pb.Comment{File: "a.go", Line: 3, Text: "line"})
+
+	require.Equal(t, "global\na.go: file\na.go:3: line\n", out.String())
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// maxMessageSize mirrors the message size limit used by pb.DialContext
+const maxMessageSize = 100 * 1024 * 1024 // 100MB
+
+// serverTLSEnabled reports if the gRPC server should use TLS
+func (c config) serverTLSEnabled() bool {
+	return c.TLSCert != "" || c.TLSKey != ""
+}
+
+// dataServiceTLSEnabled reports if the connection to DataService should use TLS
+func (c config) dataServiceTLSEnabled() bool {
+	return c.DataServiceTLS ||
+		c.DataServiceTLSCA != "" ||
+		c.DataServiceTLSCert != "" ||
+		c.DataServiceTLSKey != ""
+}
+
+// serverCredentials returns transport credentials for the analyzer gRPC server.
+// nil is returned if TLS is not configured. When a client CA is given,
+// clients are required to present a certificate signed by it.
+func serverCredentials(c config) (credentials.TransportCredentials, error) {
+	if !c.serverTLSEnabled() {
+		if c.TLSClientCA != "" {
+			return nil, fmt.Errorf("client CA is set but server certificate is not")
+		}
+
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load server key pair: %s", err)
+	}
+
+	tlsConf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.TLSClientCA != "" {
+		pool, err := loadCertPool(c.TLSClientCA)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConf.ClientCAs = pool
+		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(tlsConf), nil
+}
+
+// dataServiceCredentials returns transport credentials for the DataService client.
+// nil is returned if TLS is not configured. Without a CA the system roots are used.
+func dataServiceCredentials(c config) (credentials.TransportCredentials, error) {
+	if !c.dataServiceTLSEnabled() {
+		return nil, nil
+	}
+
+	tlsConf := &tls.Config{
+		ServerName: c.DataServiceTLSServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if c.DataServiceTLSCA != "" {
+		pool, err := loadCertPool(c.DataServiceTLSCA)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConf.RootCAs = pool
+	}
+
+	if c.DataServiceTLSCert != "" || c.DataServiceTLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(c.DataServiceTLSCert, c.DataServiceTLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load DataService client key pair: %s", err)
+		}
+
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConf), nil
+}
+
+// loadCertPool reads PEM encoded certificates from a file
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CA file: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// dialContext creates a client connection like pb.DialContextWithInterceptors
+// does, but with the given transport credentials. pb.DialContextWithInterceptors
+// always dials insecure, so it's only used when creds is nil.
+func dialContext(
+	ctx context.Context,
+	target string,
+	creds credentials.TransportCredentials,
+	streamInterceptors []grpc.StreamClientInterceptor,
+	unaryInterceptors []grpc.UnaryClientInterceptor,
+	opts ...grpc.DialOption,
+) (*grpc.ClientConn, error) {
+	if creds == nil {
+		return pb.DialContextWithInterceptors(ctx, target,
+			streamInterceptors, unaryInterceptors, opts...)
+	}
+
+	streamInterceptors = append(
+		streamInterceptors,
+		pb.CtxlogStreamClientInterceptor,
+	)
+	unaryInterceptors = append(
+		unaryInterceptors,
+		pb.CtxlogUnaryClientInterceptor,
+	)
+
+	opts = append(opts,
+		grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
+			streamInterceptors...,
+		)),
+		grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
+			unaryInterceptors...,
+		)),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(maxMessageSize),
+			grpc.MaxCallSendMsgSize(maxMessageSize),
+		),
+		grpc.WithTransportCredentials(creds),
+	)
+
+	return grpc.DialContext(ctx, target, opts...)
+}
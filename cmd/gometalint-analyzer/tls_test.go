@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	// paths to PEM files
+	certPath string
+	keyPath  string
+}
+
+// newTestCert generates a certificate signed by parent, or self-signed CA if parent is nil
+func newTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	require := require.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signerCert, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signerCert, &key.PublicKey, signerKey)
+	require.NoError(err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(err)
+
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certPath: filepath.Join(dir, name+".crt"),
+		keyPath:  filepath.Join(dir, name+".key"),
+	}
+
+	require.NoError(ioutil.WriteFile(c.certPath,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(ioutil.WriteFile(c.keyPath,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return c
+}
+
+type tlsFixture struct {
+	ca, server, client, otherCA, otherClient *testCert
+}
+
+func newTLSFixture(t *testing.T) (*tlsFixture, func()) {
+	dir, err := ioutil.TempDir("", "gometalint-tls")
+	require.NoError(t, err)
+
+	f := &tlsFixture{}
+	f.ca = newTestCert(t, dir, "ca", nil)
+	f.server = newTestCert(t, dir, "server", f.ca)
+	f.client = newTestCert(t, dir, "client", f.ca)
+	f.otherCA = newTestCert(t, dir, "other-ca", nil)
+	f.otherClient = newTestCert(t, dir, "other-client", f.otherCA)
+
+	return f, func() { os.RemoveAll(dir) }
+}
+
+// startServer runs the analyzer gRPC server configured with conf and returns its address
+func startServer(t *testing.T, conf config) (string, func()) {
+	creds, err := serverCredentials(conf)
+	require.NoError(t, err)
+
+	var opts []grpc.ServerOption
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	server := pb.NewServerWithInterceptors(nil, nil, opts...)
+	pb.RegisterAnalyzerServer(server, &gometalint.Analyzer{Version: "test"})
+
+	lis, err := pb.Listen("ipv4://127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+
+	return lis.Addr().String(), server.Stop
+}
+
+// call dials addr with client side of conf and sends a push event
+func call(t *testing.T, addr string, conf config) error {
+	creds, err := dataServiceCredentials(conf)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := dialContext(ctx, addr, creds, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = pb.NewAnalyzerClient(conn).NotifyPushEvent(ctx, &pb.PushEvent{})
+	return err
+}
+
+func TestTLSDisabled(t *testing.T) {
+	require := require.New(t)
+
+	addr, stop := startServer(t, config{})
+	defer stop()
+
+	require.NoError(call(t, addr, config{}))
+}
+
+func TestTLS(t *testing.T) {
+	require := require.New(t)
+	f, cleanup := newTLSFixture(t)
+	defer cleanup()
+
+	addr, stop := startServer(t, config{
+		TLSCert: f.server.certPath,
+		TLSKey:  f.server.keyPath,
+	})
+	defer stop()
+
+	require.NoError(call(t, addr, config{
+		DataServiceTLSCA: f.ca.certPath,
+	}))
+
+	require.Error(call(t, addr, config{
+		DataServiceTLSCA: f.otherCA.certPath,
+	}), "server certificate must be verified")
+}
+
+func TestMutualTLS(t *testing.T) {
+	require := require.New(t)
+	f, cleanup := newTLSFixture(t)
+	defer cleanup()
+
+	addr, stop := startServer(t, config{
+		TLSCert:     f.server.certPath,
+		TLSKey:      f.server.keyPath,
+		TLSClientCA: f.ca.certPath,
+	})
+	defer stop()
+
+	require.NoError(call(t, addr, config{
+		DataServiceTLSCA:   f.ca.certPath,
+		DataServiceTLSCert: f.client.certPath,
+		DataServiceTLSKey:  f.client.keyPath,
+	}))
+
+	require.Error(call(t, addr, config{
+		DataServiceTLSCA: f.ca.certPath,
+	}), "client without certificate must be rejected")
+
+	require.Error(call(t, addr, config{
+		DataServiceTLSCA:   f.ca.certPath,
+		DataServiceTLSCert: f.otherClient.certPath,
+		DataServiceTLSKey:  f.otherClient.keyPath,
+	}), "client certificate signed by unknown CA must be rejected")
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := serverCredentials(config{TLSClientCA: "ca.crt"})
+	require.Error(err)
+
+	_, err = serverCredentials(config{TLSCert: "missing.crt", TLSKey: "missing.key"})
+	require.Error(err)
+
+	_, err = dataServiceCredentials(config{DataServiceTLSCA: "missing.crt"})
+	require.Error(err)
+
+	creds, err := dataServiceCredentials(config{DataServiceTLS: true})
+	require.NoError(err)
+	require.NotNil(creds)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
+
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var validateUsageMessage = fmt.Sprintf(`usage: %s validate-config [--analyzer NAME] [PATH]
+
+Checks settings of the analyzer in lookout configuration file,
+.lookout.yml by default, and prints the errors.
+
+`, name)
+
+// lookoutConfig is the part of .lookout.yml with settings of analyzers
+type lookoutConfig struct {
+	Analyzers []struct {
+		Name     string                 `yaml:"name"`
+		Settings map[string]interface{} `yaml:"settings"`
+	} `yaml:"analyzers"`
+}
+
+// runValidateConfig validates settings of the analyzer in lookout configuration
+// file and prints errors to out. Error is returned if the settings are wrong.
+func runValidateConfig(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), validateUsageMessage)
+		flags.PrintDefaults()
+	}
+
+	analyzer := flags.String("analyzer", "gometalint", "name of the analyzer in the configuration")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+
+		return err
+	}
+
+	path := ".lookout.yml"
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		path = flags.Arg(0)
+	default:
+		flags.Usage()
+		return fmt.Errorf("only one configuration file can be validated")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var conf lookoutConfig
+	if err := yaml.Unmarshal(content, &conf); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	found := false
+	var errs []gometalint.ConfigError
+	for _, a := range conf.Analyzers {
+		if a.Name != *analyzer {
+			continue
+		}
+
+		found = true
+		if s := pb.ToStruct(a.Settings); s != nil {
+			errs = append(errs, gometalint.ValidateConfig(*s)...)
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("%s: analyzer %q is not found", path, *analyzer)
+	}
+
+	for _, err := range errs {
+		fmt.Fprintf(out, "%s: settings.%s\n", path, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %d errors found", path, len(errs))
+	}
+
+	fmt.Fprintf(out, "%s: settings of %s are valid\n", path, *analyzer)
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const validLookoutConfig = `analyzers:
+  - name: style
+    addr: ipv4://localhost:9931
+    settings:
+      unknown: true
+  - name: gometalint
+    addr: ipv4://localhost:9930
+    settings:
+      linters:
+        - name: lll
+          maxLen: 120
+      overrides:
+        - paths: ["cmd/*"]
+          linters:
+            - name: gocyclo
+              enabled: false
+`
+
+const wrongLookoutConfig = `analyzers:
+  - name: gometalint
+    addr: ipv4://localhost:9930
+    settings:
+      linters:
+        - name: lll
+          maxLen: long
+      summary: yes please
+`
+
+func TestValidateConfig(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-config")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "valid.yml")
+	require.NoError(ioutil.WriteFile(valid, []byte(validLookoutConfig), 0644))
+	wrong := filepath.Join(dir, "wrong.yml")
+	require.NoError(ioutil.WriteFile(wrong, []byte(wrongLookoutConfig), 0644))
+
+	var out bytes.Buffer
+	require.NoError(runValidateConfig([]string{valid}, &out))
+	require.Equal(valid+": settings of gometalint are valid\n", out.String())
+
+	out.Reset()
+	require.EqualError(runValidateConfig([]string{wrong}, &out), wrong+": 2 errors found")
+	require.Equal(wrong+": settings.linters[0].maxLen: must be an integer\n"+
+		wrong+": settings.summary: must be a boolean\n", out.String())
+
+	err = runValidateConfig([]string{"--analyzer", "other", valid}, &out)
+	require.EqualError(err, valid+": analyzer \"other\" is not found")
+
+	require.Error(runValidateConfig([]string{filepath.Join(dir, "missing.yml")}, &out))
+	require.Error(runValidateConfig([]string{valid, wrong}, &out))
+}
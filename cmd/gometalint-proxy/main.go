@@ -1,15 +1,202 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/src-d/lookout-gometalint-analyzer"
+	"github.com/src-d/lookout-gometalint-analyzer/format"
 
 	log "gopkg.in/src-d/go-log.v1"
 )
 
+var usageMessage = fmt.Sprintf(`usage: %s [OPTIONS] [--] [GOMETALINTER_ARGS]
+
+%s runs gometalinter with the analyzer's set of linters and writes found issues.
+Arguments that are not options of %s are passed to gometalinter as well.
+
+Exit codes:
+  %d  no issues over the threshold
+  %d  issues over the threshold
+  %d  gometalinter or %s failed
+
+`, name, name, name, exitClean, exitIssues, exitFailure, name)
+
+const name = "gometalint-proxy"
+
+// exit codes
+const (
+	exitClean   = 0
+	exitIssues  = 1
+	exitFailure = 2
+)
+
+// severities in ascending order, issues with unknown severity are the lowest
+var severities = []string{"warning", "error"}
+
+func severityRank(severity string) int {
+	for i, s := range severities {
+		if s == severity {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
 func main() {
-	withArgs := append([]string(nil), os.Args[1:]...)
-	comments := gometalint.RunGometalinter(withArgs)
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the proxy with the given arguments and returns exit code.
+// Issues are written to stdout, usage and flag errors to stderr.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usageMessage)
+		flags.PrintDefaults()
+	}
+
+	formatFlag := flags.String("format", "text",
+		"output format: "+strings.Join(format.Names(), ", "))
+	outputFlag := flags.String("output", "", "write issues to the file instead of stdout")
+	failOnFlag := flags.String("fail-on", "none", fmt.Sprintf(
+		"minimal severity of issues counted against --max-issues: none, %s",
+		strings.Join(severities, ", ")))
+	maxIssuesFlag := flags.Int("max-issues", -1,
+		"maximal number of counted issues, all issues are counted if --fail-on is none (default 0 if --fail-on is set)")
+	own, rest := splitArgs(flags, args)
+	if err := flags.Parse(own); err != nil {
+		if err == flag.ErrHelp {
+			return exitClean
+		}
+
+		return exitFailure
+	}
+
+	formatter, err := format.Get(*formatFlag)
+	if err != nil {
+		log.Errorf(err, "wrong format")
+		return exitFailure
+	}
+
+	threshold, err := newThreshold(*failOnFlag, *maxIssuesFlag)
+	if err != nil {
+		log.Errorf(err, "wrong threshold")
+		return exitFailure
+	}
+
+	comments, runErr := gometalint.RunGometalinterErr(rest)
 	log.Infof("%d issues found\n", len(comments))
+
+	out := stdout
+	if *outputFlag != "" {
+		f, err := os.Create(*outputFlag)
+		if err != nil {
+			log.Errorf(err, "cannot create output file %s", *outputFlag)
+			return exitFailure
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	if err := formatter(out, comments); err != nil {
+		log.Errorf(err, "failed to write issues")
+		return exitFailure
+	}
+
+	if runErr != nil {
+		log.Errorf(runErr, "gometalinter failed")
+		return exitFailure
+	}
+
+	if threshold.exceeded(comments) {
+		log.Infof("issues are over the threshold")
+		return exitIssues
+	}
+
+	return exitClean
+}
+
+// splitArgs separates options of the proxy from the arguments passed
+// to gometalinter, the order of both is kept; all arguments after "--"
+// are passed to gometalinter
+func splitArgs(flags *flag.FlagSet, args []string) (own, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return own, append(rest, args[i+1:]...)
+		}
+
+		if len(arg) < 2 || arg[0] != '-' {
+			rest = append(rest, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name = name[:eq]
+		}
+
+		if name != "h" && name != "help" && flags.Lookup(name) == nil {
+			rest = append(rest, arg)
+			continue
+		}
+
+		own = append(own, arg)
+		// the value of the option may be the next argument
+		if !strings.Contains(arg, "=") && flags.Lookup(name) != nil && i+1 < len(args) {
+			i++
+			own = append(own, args[i])
+		}
+	}
+
+	return own, rest
+}
+
+// threshold decides when found issues should fail the run
+type threshold struct {
+	// minRank is the lowest severity rank of counted issues,
+	// -1 to count all issues
+	minRank int
+	// maxIssues is the number of counted issues allowed,
+	// -1 for unlimited
+	maxIssues int
+}
+
+func newThreshold(failOn string, maxIssues int) (threshold, error) {
+	if failOn == "none" || failOn == "" {
+		return threshold{minRank: -1, maxIssues: maxIssues}, nil
+	}
+
+	rank := severityRank(failOn)
+	if rank == 0 {
+		return threshold{}, fmt.Errorf("unknown severity %q", failOn)
+	}
+
+	if maxIssues < 0 {
+		maxIssues = 0
+	}
+
+	return threshold{minRank: rank, maxIssues: maxIssues}, nil
+}
+
+func (t threshold) exceeded(comments []gometalint.Comment) bool {
+	if t.maxIssues < 0 {
+		return false
+	}
+
+	count := 0
+	for _, c := range comments {
+		if severityRank(c.Level()) >= t.minRank {
+			count++
+		}
+	}
+
+	return count > t.maxIssues
 }
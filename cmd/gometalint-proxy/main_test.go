@@ -1,26 +1,184 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/kami-zh/go-capturer"
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
 	"github.com/stretchr/testify/require"
 )
 
 func TestMain(t *testing.T) {
 	require := require.New(t)
-	os.Args = []string{"-h"}
 
 	var stdout, stderr string
+	var code int
 
 	stdout = capturer.CaptureStdout(func() {
 		stderr = capturer.CaptureStderr(func() {
-			main()
+			code = run([]string{"-h"}, os.Stdout, os.Stderr)
 		})
 	})
 
-	require.Empty(stderr)
-	require.True(strings.HasPrefix("usage: ", stdout))
+	require.Empty(stdout)
+	require.True(strings.HasPrefix(stderr, "usage: "))
+	require.Equal(exitClean, code)
+}
+
+// withFakeGometalinter puts a shell script in place of gometalinter binary in PATH
+func withFakeGometalinter(t *testing.T, script string) func() {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gometalinter requires sh")
+	}
+
+	dir, err := ioutil.TempDir("", "gometalint-proxy")
+	require.NoError(t, err)
+
+	fake := filepath.Join(dir, "gometalinter.v2")
+	require.NoError(t, ioutil.WriteFile(fake, []byte("#!/bin/sh\n"+script), 0755))
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	return func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(dir)
+	}
+}
+
+const issues = `echo 'a.go:3::warning: line is 120 characters (lll)'
+echo 'a.go:5::warning: line is 100 characters (lll)'
+echo 'b.go:7:1:error: Errors unhandled.,LOW,HIGH (gosec)'
+exit 1
+`
+
+var exitCodeTests = []struct {
+	name   string
+	script string
+	args   []string
+	code   int
+}{
+	{"clean", "exit 0", nil, exitClean},
+	{"clean with threshold", "exit 0", []string{"--fail-on=warning"}, exitClean},
+	{"issues without threshold", issues, nil, exitClean},
+	{"fail on warning", issues, []string{"--fail-on=warning"}, exitIssues},
+	{"fail on error", issues, []string{"--fail-on=error"}, exitIssues},
+	{"errors under max", issues, []string{"--fail-on=error", "--max-issues=1"}, exitClean},
+	{"warnings over max", issues, []string{"--fail-on=warning", "--max-issues=2"}, exitIssues},
+	{"all issues under max", issues, []string{"--max-issues=3"}, exitClean},
+	{"all issues over max", issues, []string{"--max-issues=2"}, exitIssues},
+	{"tool failed", "echo 'crashed' >&2; exit 2", nil, exitFailure},
+	{"tool failed with issues", strings.Replace(issues, "exit 1", "exit 3", 1), []string{"--fail-on=error"}, exitFailure},
+	{"nothing found with errors", "echo 'bad flag' >&2; exit 1", nil, exitFailure},
+	{"unknown severity", "exit 0", []string{"--fail-on=fatal"}, exitFailure},
+	{"unknown format", "exit 0", []string{"--format=html"}, exitFailure},
+	{"gometalinter flag", "exit 0", []string{"--unknown"}, exitClean},
+	{"missing value", "exit 0", []string{"--format"}, exitFailure},
+}
+
+func TestExitCodes(t *testing.T) {
+	for _, tt := range exitCodeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer withFakeGometalinter(t, tt.script)()
+
+			var stdout, stderr bytes.Buffer
+			require.Equal(t, tt.code, run(tt.args, &stdout, &stderr), stderr.String())
+		})
+	}
+}
+
+func TestOutput(t *testing.T) {
+	require := require.New(t)
+	defer withFakeGometalinter(t, issues)()
+
+	dir, err := ioutil.TempDir("", "gometalint-proxy-out")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "issues.txt")
+	var stdout, stderr bytes.Buffer
+	require.Equal(exitClean, run([]string{"--format=text", "--output=" + out, "--", "./..."}, &stdout, &stderr))
+	require.Empty(stdout.String())
+
+	content, err := ioutil.ReadFile(out)
+	require.NoError(err)
+	require.Equal(`a.go:3::warning: line is 120 characters (lll)
+a.go:5::warning: line is 100 characters (lll)
+b.go:7:1:error: Errors unhandled.,LOW,HIGH (gosec)
+`, string(content))
+}
+
+func TestFlagErrors(t *testing.T) {
+	require := require.New(t)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(exitFailure, run([]string{"--format"}, &stdout, &stderr))
+	require.Empty(stdout.String(), "stdout is reserved for issues")
+	require.Contains(stderr.String(), "flag needs an argument")
+	require.Contains(stderr.String(), "usage: ")
+}
+
+func TestSplitArgs(t *testing.T) {
+	require := require.New(t)
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.String("format", "text", "")
+	flags.String("output", "", "")
+
+	own, rest := splitArgs(flags, []string{"--format=json", "--deadline=30s", "-output", "out.txt",
+		"--vendor", "./...", "--", "--format=checkstyle"})
+	require.Equal([]string{"--format=json", "-output", "out.txt"}, own)
+	require.Equal([]string{"--deadline=30s", "--vendor", "./...", "--format=checkstyle"}, rest)
+
+	own, rest = splitArgs(flags, []string{"-h"})
+	require.Equal([]string{"-h"}, own)
+	require.Empty(rest)
+}
+
+func TestPassThrough(t *testing.T) {
+	require := require.New(t)
+	defer withFakeGometalinter(t, `for arg in "$@"; do
+	case "$arg" in
+	--deadline=*|./...) echo "a.go:1::warning: $arg (fake)";;
+	esac
+done
+exit 1
+`)()
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(exitClean, run([]string{"--format=text", "--deadline=30s", "./..."}, &stdout, &stderr))
+	require.Equal(`a.go:1::warning: --deadline=30s (fake)
+a.go:1::warning: ./... (fake)
+`, stdout.String(), "gometalinter options must be passed through without --")
+}
+
+func TestThreshold(t *testing.T) {
+	require := require.New(t)
+
+	comments := []gometalint.Comment{
+		gometalint.NewComment("warning", "a.go", 1, 0, " w (lll)"),
+		gometalint.NewComment("info", "a.go", 2, 0, " i (custom)"),
+	}
+
+	th, err := newThreshold("none", -1)
+	require.NoError(err)
+	require.False(th.exceeded(comments))
+
+	th, err = newThreshold("warning", -1)
+	require.NoError(err)
+	require.True(th.exceeded(comments))
+
+	th, err = newThreshold("warning", 1)
+	require.NoError(err)
+	require.False(th.exceeded(comments), "info issue must not be counted")
+
+	th, err = newThreshold("", 1)
+	require.NoError(err)
+	require.True(th.exceeded(comments))
 }
@@ -0,0 +1,121 @@
+package gometalint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// commentFormat is the format of texts of posted comments
+type commentFormat string
+
+const (
+	// formatRaw posts messages as printed by gometalint, it's the default
+	formatRaw commentFormat = "raw"
+	// formatMarkdown posts messages with the linter, rule and links in markdown
+	formatMarkdown commentFormat = "markdown"
+	// formatPlain posts the same information as formatMarkdown in plain text
+	formatPlain commentFormat = "plain"
+)
+
+// commentFormatConfig reads "commentFormat" option of the configuration
+func commentFormatConfig(logger log.Logger, s types.Struct) commentFormat {
+	v, ok := s.GetFields()["commentFormat"]
+	if !ok || v == nil {
+		return formatRaw
+	}
+
+	switch f := commentFormat(v.GetStringValue()); f {
+	case formatRaw, formatMarkdown, formatPlain:
+		return f
+	default:
+		logger.Warningf("wrong value for commentFormat argument")
+		return formatRaw
+	}
+}
+
+var (
+	// rulePrefix matches rule ID before the message, e.g. "G104: Errors unhandled."
+	rulePrefix = regexp.MustCompile(`^([A-Z]+\d+):\s*`)
+	// ruleSuffix matches rule ID after the message, e.g. "x is unused (U1000)"
+	ruleSuffix = regexp.MustCompile(`\s*\(([A-Z]+\d+)\)$`)
+)
+
+// splitRule returns ID of the rule the comment is about, if the linter
+// reports it, and the message without the ID
+func splitRule(c Comment) (rule, message string) {
+	message = c.Message()
+	for _, re := range []*regexp.Regexp{rulePrefix, ruleSuffix} {
+		if m := re.FindStringSubmatch(message); m != nil {
+			return m[1], re.ReplaceAllString(message, "")
+		}
+	}
+
+	return "", message
+}
+
+// docsURL returns link to the documentation of the linter rule, empty if unknown
+func docsURL(linter, rule string) string {
+	switch linter {
+	case "gosec":
+		if rule != "" {
+			return fmt.Sprintf("https://securego.io/docs/rules/%s.html", strings.ToLower(rule))
+		}
+	case "staticcheck", "gosimple", "unused", "megacheck":
+		if rule != "" {
+			return fmt.Sprintf("https://staticcheck.io/docs/checks#%s", rule)
+		}
+	case "misspell":
+		return "https://github.com/client9/misspell/blob/master/words.go"
+	}
+
+	return ""
+}
+
+// text returns text of the comment in the format
+func (f commentFormat) text(c Comment) string {
+	linter := c.Linter()
+	if f == formatRaw || linter == "" {
+		return c.text
+	}
+
+	rule, message := splitRule(c)
+	url := docsURL(linter, rule)
+	nolint := "// nolint: " + linter
+
+	if f == formatPlain {
+		lines := []string{fmt.Sprintf("[%s] %s", linter, message)}
+		if rule != "" {
+			lines[0] = fmt.Sprintf("[%s] %s: %s", linter, rule, message)
+		}
+
+		if url != "" {
+			lines = append(lines, "Documentation: "+url)
+		}
+
+		lines = append(lines, fmt.Sprintf("To suppress, add %q at the end of the line.", nolint))
+		return strings.Join(lines, "\n")
+	}
+
+	header := fmt.Sprintf("**`%s`**", linter)
+	if rule != "" {
+		header += fmt.Sprintf(" `%s`", rule)
+	}
+
+	paragraphs := []string{header + " " + message}
+	if url != "" {
+		paragraphs = append(paragraphs, fmt.Sprintf("[Documentation](%s)", url))
+	}
+
+	paragraphs = append(paragraphs, fmt.Sprintf("To suppress, add `%s` at the end of the line.", nolint))
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// withFingerprint appends the fingerprint of the issue to the text of
+// the comment, hidden from readers, to track the comment across revisions
+func withFingerprint(text, fingerprint string) string {
+	return fmt.Sprintf("%s\n\n<!-- fingerprint: %s -->", text, fingerprint)
+}
@@ -0,0 +1,88 @@
+package gometalint
+
+import (
+	"testing"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestCommentFormatConfig(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(formatRaw, commentFormatConfig(logger, types.Struct{}))
+	require.Equal(formatMarkdown, commentFormatConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"commentFormat": "markdown",
+	})))
+	require.Equal(formatPlain, commentFormatConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"commentFormat": "plain",
+	})))
+	require.Equal(formatRaw, commentFormatConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"commentFormat": "raw",
+	})))
+	require.Equal(formatRaw, commentFormatConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"commentFormat": "html",
+	})))
+}
+
+var commentTextTests = []struct {
+	text     string
+	markdown string
+	plain    string
+}{
+	{
+		" G104: Errors unhandled. (gosec)",
+		"**`gosec`** `G104` Errors unhandled.\n\n" +
+			"[Documentation](https://securego.io/docs/rules/g104.html)\n\n" +
+			"To suppress, add `// nolint: gosec` at the end of the line.",
+		"[gosec] G104: Errors unhandled.\n" +
+			"Documentation: https://securego.io/docs/rules/g104.html\n" +
+			"To suppress, add \"// nolint: gosec\" at the end of the line.",
+	},
+	{
+		" this value of x is never used (SA4006) (staticcheck)",
+		"**`staticcheck`** `SA4006` this value of x is never used\n\n" +
+			"[Documentation](https://staticcheck.io/docs/checks#SA4006)\n\n" +
+			"To suppress, add `// nolint: staticcheck` at the end of the line.",
+		"[staticcheck] SA4006: this value of x is never used\n" +
+			"Documentation: https://staticcheck.io/docs/checks#SA4006\n" +
+			"To suppress, add \"// nolint: staticcheck\" at the end of the line.",
+	},
+	{
+		` "langauge" is a misspelling of "language" (misspell)`,
+		"**`misspell`** \"langauge\" is a misspelling of \"language\"\n\n" +
+			"[Documentation](https://github.com/client9/misspell/blob/master/words.go)\n\n" +
+			"To suppress, add `// nolint: misspell` at the end of the line.",
+		"[misspell] \"langauge\" is a misspelling of \"language\"\n" +
+			"Documentation: https://github.com/client9/misspell/blob/master/words.go\n" +
+			"To suppress, add \"// nolint: misspell\" at the end of the line.",
+	},
+	{
+		" cyclomatic complexity 12 of func `f` is high (> 10) (gocyclo)",
+		"**`gocyclo`** cyclomatic complexity 12 of func `f` is high (> 10)\n\n" +
+			"To suppress, add `// nolint: gocyclo` at the end of the line.",
+		"[gocyclo] cyclomatic complexity 12 of func `f` is high (> 10)\n" +
+			"To suppress, add \"// nolint: gocyclo\" at the end of the line.",
+	},
+	{
+		" no linter",
+		" no linter",
+		" no linter",
+	},
+}
+
+func TestCommentText(t *testing.T) {
+	for _, tt := range commentTextTests {
+		t.Run(tt.text, func(t *testing.T) {
+			c := NewComment("warning", "a.go", 1, 0, tt.text)
+			require.Equal(t, tt.text, formatRaw.text(c))
+			require.Equal(t, tt.markdown, formatMarkdown.text(c))
+			require.Equal(t, tt.plain, formatPlain.text(c))
+		})
+	}
+}
+
+func TestWithFingerprint(t *testing.T) {
+	require.Equal(t, " long line (lll)\n\n<!-- fingerprint: abc -->", withFingerprint(" long line (lll)", "abc"))
+}
@@ -0,0 +1,599 @@
+package gometalint
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// CustomLinter is a linter defined in the server configuration
+type CustomLinter struct {
+	Name string `json:"name" yaml:"name"`
+	// Command is the template of the command line with words separated by
+	// spaces. {path} is replaced with the directory of the analyzed files
+	// and {args} word with arguments of the options.
+	Command string `json:"command" yaml:"command"`
+	// Pattern is the regexp matching issues in lines of the output with
+	// named groups path, line, col, severity and message
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// JSON maps fields of JSON objects printed by the linter to issues,
+	// it's used instead of Pattern
+	JSON *JSONMapping `json:"json" yaml:"json"`
+	// Severity of issues the linter doesn't report severity of, "warning" by default
+	Severity string `json:"severity" yaml:"severity"`
+	// Options of the linter in the repository configuration by name
+	Options map[string]CustomOption `json:"options" yaml:"options"`
+	// Gometalinter runs the linter by gometalinter passing it with --linter
+	// argument instead of running it directly. gometalinter appends paths to
+	// the command, so it must not contain {path}.
+	Gometalinter bool `json:"gometalinter" yaml:"gometalinter"`
+}
+
+// JSONMapping names fields of issues in JSON output of a linter,
+// names of nested fields are separated by dots
+type JSONMapping struct {
+	Path     string `json:"path" yaml:"path"`
+	Line     string `json:"line" yaml:"line"`
+	Column   string `json:"column" yaml:"column"`
+	Message  string `json:"message" yaml:"message"`
+	Severity string `json:"severity" yaml:"severity"`
+}
+
+// CustomOption is an option of a custom linter
+type CustomOption struct {
+	// Type of the value: "int", "number", "string" or "bool"
+	Type string `json:"type" yaml:"type"`
+	// Argument is the template of the argument, {value} is replaced with
+	// the value. Argument of a bool option is passed if the value is true.
+	Argument string `json:"argument" yaml:"argument"`
+	// Default is the value used if the option is not set,
+	// the argument is not passed if there is no default
+	Default interface{} `json:"default" yaml:"default"`
+}
+
+// custom linters set by SetCustomLinters
+var (
+	customMu      sync.RWMutex
+	customLinters []CustomLinter
+)
+
+// LoadCustomLinters reads definitions of custom linters from "linters" list
+// of YAML or JSON file
+func LoadCustomLinters(path string) ([]CustomLinter, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Linters []CustomLinter `json:"linters" yaml:"linters"`
+	}
+
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(content, &file)
+	} else {
+		err = yaml.UnmarshalStrict(content, &file)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return file.Linters, nil
+}
+
+// SetCustomLinters validates the linters and makes them run
+// in addition to the default ones
+func SetCustomLinters(linters []CustomLinter) error {
+	var errs []string
+	names := make(map[string]bool)
+	for i, l := range linters {
+		p := fmt.Sprintf("linters[%d]", i)
+		if names[l.Name] {
+			errs = append(errs, ConfigError{childPath(p, "name"),
+				fmt.Sprintf("duplicate linter %q", l.Name)}.Error())
+		}
+		names[l.Name] = true
+
+		for _, err := range l.validate(p) {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("wrong custom linters: %s", strings.Join(errs, "; "))
+	}
+
+	customMu.Lock()
+	defer customMu.Unlock()
+	customLinters = append([]CustomLinter(nil), linters...)
+	return nil
+}
+
+// customLinter returns the custom linter with the name
+func customLinter(name string) (CustomLinter, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+
+	for _, l := range customLinters {
+		if l.Name == name {
+			return l, true
+		}
+	}
+
+	return CustomLinter{}, false
+}
+
+// customLinterNames returns names of the custom linters in order of definition
+func customLinterNames() []string {
+	customMu.RLock()
+	defer customMu.RUnlock()
+
+	var names []string
+	for _, l := range customLinters {
+		names = append(names, l.Name)
+	}
+
+	return names
+}
+
+var (
+	// linterName matches names gometalint accepts in issues
+	linterName = regexp.MustCompile(`^[\w-]+$`)
+	// reservedOptions are options of every linter
+	reservedOptions = []string{"name", enabledOption, "exclude"}
+)
+
+// validate checks the definition of the linter at the path
+func (l CustomLinter) validate(p string) []ConfigError {
+	var errs []ConfigError
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, ConfigError{childPath(p, field), fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case !linterName.MatchString(l.Name):
+		fail("name", "must consist of letters, digits, '_' and '-'")
+	case isDefaultLinter(l.Name):
+		fail("name", "linter %q is already defined", l.Name)
+	}
+
+	words := strings.Fields(l.Command)
+	switch {
+	case len(words) == 0:
+		fail("command", "is required")
+	case l.Gometalinter && strings.Contains(l.Command, "{path}"):
+		fail("command", "must not contain {path}, gometalinter appends paths")
+	case l.Gometalinter && strings.Contains(l.Command, ":"):
+		fail("command", "must not contain ':' to be passed to gometalinter")
+	case !l.Gometalinter && !strings.Contains(l.Command, "{path}"):
+		fail("command", "must contain {path}")
+	case len(l.Options) > 0 && !containsString(words, "{args}"):
+		fail("command", "must contain {args} to pass options")
+	}
+
+	switch {
+	case l.Gometalinter && l.JSON != nil:
+		fail("json", "is not supported by gometalinter")
+	case l.Pattern == "" && l.JSON == nil:
+		fail("pattern", "pattern or json is required")
+	case l.Pattern != "" && l.JSON != nil:
+		fail("json", "only one of pattern and json can be set")
+	}
+
+	if l.Pattern != "" {
+		re, err := regexp.Compile(l.Pattern)
+		if err != nil {
+			fail("pattern", "%s", err)
+		} else {
+			for _, group := range []string{"path", "line", "message"} {
+				if !containsString(re.SubexpNames(), group) {
+					fail("pattern", "must have %q named group", group)
+				}
+			}
+		}
+	}
+
+	if l.JSON != nil {
+		if l.JSON.Path == "" {
+			fail("json.path", "is required")
+		}
+
+		if l.JSON.Line == "" {
+			fail("json.line", "is required")
+		}
+
+		if l.JSON.Message == "" {
+			fail("json.message", "is required")
+		}
+	}
+
+	switch l.Severity {
+	case "", "warning", "error":
+	default:
+		fail("severity", "must be one of %s", quoteList([]string{"warning", "error"}))
+	}
+
+	var names []string
+	for name := range l.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		o := l.Options[name]
+		op := childPath(p, "options."+name)
+		_, ok := customOptionSchemas[o.Type]
+		schema := l.optionSchema(o)
+		switch {
+		case containsString(reservedOptions, name):
+			fail("options."+name, "option of every linter can't be redefined")
+		case !ok:
+			fail("options."+name+".type", "must be one of %s", quoteList(customOptionTypes()))
+		case o.Argument == "":
+			fail("options."+name+".argument", "is required")
+		case o.Type != "bool" && !strings.Contains(o.Argument, "{value}"):
+			fail("options."+name+".argument", "must contain {value}")
+		case o.Default != nil:
+			errs = append(errs, schema.validate(childPath(op, "default"), pb.ToValue(o.Default))...)
+		}
+	}
+
+	return errs
+}
+
+// containsString returns true if the string is in the list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// customOptionSchemas are schemas of custom options by type
+var customOptionSchemas = map[string]valueSchema{
+	"int":    intSchema{min: math.MinInt32},
+	"number": numberSchema{},
+	"string": stringSchema{},
+	"bool":   boolSchema{},
+}
+
+// gometalinterValue matches string values of options safe to pass in
+// the command of --linter argument of gometalinter, which is split by spaces
+// and separated from the name and the pattern by ':'
+var gometalinterValue = regexp.MustCompile(`^[\w.,=+@%/~-]*$`)
+
+// gometalinterStringSchema accepts string values of options
+// of linters run by gometalinter
+var gometalinterStringSchema = stringSchema{check: func(s string) error {
+	if !gometalinterValue.MatchString(s) {
+		return fmt.Errorf("must consist of letters, digits and %q to be passed to gometalinter", "_.,=+@%/~-")
+	}
+
+	return nil
+}}
+
+// customOptionTypes returns sorted types of custom options
+func customOptionTypes() []string {
+	var names []string
+	for name := range customOptionSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// optionSchema returns the schema of values of the option
+func (l CustomLinter) optionSchema(o CustomOption) valueSchema {
+	if l.Gometalinter && o.Type == "string" {
+		return gometalinterStringSchema
+	}
+
+	return customOptionSchemas[o.Type]
+}
+
+// options returns options of the linter for repository configuration
+func (l CustomLinter) options() map[string]linterOption {
+	opts := make(map[string]linterOption, len(l.Options))
+	for name, o := range l.Options {
+		schema := l.optionSchema(o)
+		opts[name] = linterOption{
+			schema:   schema,
+			argument: o.argumentConstructor(l.Name+":"+name, schema),
+		}
+	}
+
+	return opts
+}
+
+// argumentConstructor returns constructor of the argument of the option,
+// option is the name of the option used in logs; values of strings
+// not accepted by the schema are ignored
+func (o CustomOption) argumentConstructor(option string, schema valueSchema) argumentConstructor {
+	return func(logger log.Logger, v *types.Value) string {
+		var value string
+		ok := true
+		switch o.Type {
+		case "int":
+			var n int
+			n, ok = intValue(v)
+			value = strconv.Itoa(n)
+		case "number":
+			var n float64
+			n, ok = numberValue(v)
+			value = strconv.FormatFloat(n, 'f', -1, 64)
+		case "string":
+			var sv *types.Value_StringValue
+			sv, ok = v.GetKind().(*types.Value_StringValue)
+			if ok {
+				if errs := schema.validate(option, v); len(errs) > 0 {
+					logger.Warningf("wrong value for %s", errs[0])
+					return ""
+				}
+
+				value = sv.StringValue
+			}
+		case "bool":
+			var bv *types.Value_BoolValue
+			bv, ok = v.GetKind().(*types.Value_BoolValue)
+			if ok && !bv.BoolValue {
+				return ""
+			}
+		}
+
+		if !ok {
+			logger.Warningf("wrong type for %s argument", option)
+			return ""
+		}
+
+		return strings.Replace(o.Argument, "{value}", value, -1)
+	}
+}
+
+// arguments returns arguments of the options in stable order,
+// default values are used for options that are not set
+func (l CustomLinter) arguments(logger log.Logger, opts map[string]*types.Value) []string {
+	var names []string
+	for name := range l.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	constructors := l.options()
+	var args []string
+	for _, name := range names {
+		v, ok := opts[name]
+		if !ok || v == nil {
+			if l.Options[name].Default == nil {
+				continue
+			}
+
+			v = pb.ToValue(l.Options[name].Default)
+		}
+
+		if arg := constructors[name].argument(logger, v); arg != "" {
+			args = append(args, arg)
+		}
+	}
+
+	return args
+}
+
+// commandLine returns words of the command with {args} replaced by the arguments
+func (l CustomLinter) commandLine(args []string) []string {
+	var words []string
+	for _, word := range strings.Fields(l.Command) {
+		if word == "{args}" {
+			words = append(words, args...)
+		} else {
+			words = append(words, word)
+		}
+	}
+
+	return words
+}
+
+// severity returns the default severity of issues of the linter
+func (l CustomLinter) severity() string {
+	if l.Severity == "" {
+		return "warning"
+	}
+
+	return l.Severity
+}
+
+// gometalinterArguments returns arguments of gometalint defining and enabling the linter
+func (l CustomLinter) gometalinterArguments(args []string) []string {
+	return []string{
+		fmt.Sprintf("--linter=%s:%s:%s", l.Name, strings.Join(l.commandLine(args), " "), l.Pattern),
+		enablePrefix + l.Name,
+		fmt.Sprintf("--severity=%s:%s", l.Name, l.severity()),
+	}
+}
+
+// customCommand is a run of a custom linter with the arguments
+type customCommand struct {
+	linter CustomLinter
+	args   []string
+}
+
+// run runs the linter on files in the directory, in sandbox if it's not nil,
+// with the environment variables added. Issues found before an error
+// are returned anyway. The linter is killed if the context is done.
+func (c customCommand) run(ctx context.Context, dir string, sandbox *Sandbox, env []string, writable ...string) ([]Comment, error) {
+	words := c.linter.commandLine(c.args)
+	for i, word := range words {
+		words[i] = strings.Replace(word, "{path}", dir, -1)
+	}
+
+	log.Debugf("Running '%s %v'\n", words[0], words[1:])
+	out, stderr, runErr := runCommand(ctx, words[0], words[1:], sandbox, env, writable)
+
+	var comments []Comment
+	var err error
+	if c.linter.JSON != nil {
+		comments, err = c.linter.parseJSON(dir, out)
+	} else {
+		comments = c.linter.parseLines(dir, out)
+	}
+
+	if err != nil {
+		return comments, fmt.Errorf("%s: %s", c.linter.Name, err)
+	}
+
+	return comments, checkRunError(c.linter.Name, runErr, len(comments), stderr)
+}
+
+// issue returns the comment with the path relative to dir made absolute
+func (l CustomLinter) issue(dir, severity, path string, line, col int, message string) Comment {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	if severity == "" {
+		severity = l.severity()
+	}
+
+	return NewComment(severity, path, int32(line), int32(col),
+		fmt.Sprintf(" %s (%s)", strings.TrimSpace(message), l.Name))
+}
+
+// parseLines parses issues in lines of the output matching the pattern
+func (l CustomLinter) parseLines(dir string, out []byte) []Comment {
+	re := regexp.MustCompile(l.Pattern)
+	names := re.SubexpNames()
+
+	var comments []Comment
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		m := re.FindStringSubmatch(s.Text())
+		if m == nil {
+			log.Debugf("skipping output line of %s: %s", l.Name, s.Text())
+			continue
+		}
+
+		groups := make(map[string]string)
+		for i, name := range names {
+			groups[name] = m[i]
+		}
+
+		line, err := strconv.Atoi(groups["line"])
+		if err != nil {
+			log.Warningf("failed to parse line number of %s issue: %s", l.Name, s.Text())
+			continue
+		}
+
+		col, _ := strconv.Atoi(groups["col"])
+		comments = append(comments, l.issue(dir, groups["severity"], groups["path"],
+			line, col, groups["message"]))
+	}
+
+	return comments
+}
+
+// parseJSON parses issues in JSON output, the output is a sequence
+// of issue objects or lists of them
+func (l CustomLinter) parseJSON(dir string, out []byte) ([]Comment, error) {
+	var comments []Comment
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var v interface{}
+		if err := dec.Decode(&v); err == io.EOF {
+			return comments, nil
+		} else if err != nil {
+			return comments, fmt.Errorf("wrong JSON output: %s", err)
+		}
+
+		objects, ok := v.([]interface{})
+		if !ok {
+			objects = []interface{}{v}
+		}
+
+		for _, obj := range objects {
+			c, err := l.jsonIssue(dir, obj)
+			if err != nil {
+				return comments, err
+			}
+
+			comments = append(comments, c)
+		}
+	}
+}
+
+// jsonIssue converts the object of JSON output to a comment
+func (l CustomLinter) jsonIssue(dir string, obj interface{}) (Comment, error) {
+	m := l.JSON
+	path, ok := jsonField(obj, m.Path).(string)
+	if !ok {
+		return Comment{}, fmt.Errorf("no %q string field in JSON output", m.Path)
+	}
+
+	message, ok := jsonField(obj, m.Message).(string)
+	if !ok {
+		return Comment{}, fmt.Errorf("no %q string field in JSON output", m.Message)
+	}
+
+	line, ok := jsonInt(jsonField(obj, m.Line))
+	if !ok {
+		return Comment{}, fmt.Errorf("no %q integer field in JSON output", m.Line)
+	}
+
+	var col int
+	if m.Column != "" {
+		col, _ = jsonInt(jsonField(obj, m.Column))
+	}
+
+	var severity string
+	if m.Severity != "" {
+		severity, _ = jsonField(obj, m.Severity).(string)
+	}
+
+	return l.issue(dir, severity, path, line, col, message), nil
+}
+
+// jsonField returns the field of the object with dot separated name
+func jsonField(obj interface{}, name string) interface{} {
+	for _, key := range strings.Split(name, ".") {
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		obj = m[key]
+	}
+
+	return obj
+}
+
+// jsonInt converts JSON number or string to int
+func jsonInt(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case float64:
+		return int(v), v == math.Trunc(v)
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,261 @@
+package gometalint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// withCustomLinters sets custom linters for the test
+func withCustomLinters(t *testing.T, linters ...CustomLinter) func() {
+	require.NoError(t, SetCustomLinters(linters))
+	return func() {
+		require.NoError(t, SetCustomLinters(nil))
+	}
+}
+
+var golintLinter = CustomLinter{
+	Name:         "golint",
+	Command:      "golint {args}",
+	Pattern:      `^(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+): (?P<message>.*)$`,
+	Gometalinter: true,
+	Options: map[string]CustomOption{
+		"minConfidence": {Type: "number", Argument: "-min_confidence={value}", Default: 0.8},
+	},
+}
+
+var jsonLinter = CustomLinter{
+	Name:     "jsonlint",
+	Command:  "jsonlint {args} --dir={path}",
+	JSON:     &JSONMapping{Path: "pos.file", Line: "pos.line", Column: "pos.col", Message: "text", Severity: "level"},
+	Severity: "error",
+	Options: map[string]CustomOption{
+		"strict": {Type: "bool", Argument: "--strict"},
+		"depth":  {Type: "int", Argument: "--depth={value}"},
+	},
+}
+
+const customLintersYAML = `linters:
+  - name: golint
+    command: golint {args}
+    pattern: '^(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+): (?P<message>.*)$'
+    gometalinter: true
+    options:
+      minConfidence:
+        type: number
+        argument: -min_confidence={value}
+        default: 0.8
+`
+
+func TestLoadCustomLinters(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-linters")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	yamlPath := filepath.Join(dir, "linters.yml")
+	require.NoError(ioutil.WriteFile(yamlPath, []byte(customLintersYAML), 0644))
+	linters, err := LoadCustomLinters(yamlPath)
+	require.NoError(err)
+	require.Equal([]CustomLinter{golintLinter}, linters)
+
+	jsonPath := filepath.Join(dir, "linters.json")
+	require.NoError(ioutil.WriteFile(jsonPath, []byte(`{"linters": [{
+		"name": "jsonlint", "command": "jsonlint {path}", "severity": "error",
+		"json": {"path": "file", "line": "line", "message": "text"}
+	}]}`), 0644))
+	linters, err = LoadCustomLinters(jsonPath)
+	require.NoError(err)
+	require.Equal([]CustomLinter{{
+		Name: "jsonlint", Command: "jsonlint {path}", Severity: "error",
+		JSON: &JSONMapping{Path: "file", Line: "line", Message: "text"},
+	}}, linters)
+
+	require.NoError(ioutil.WriteFile(yamlPath, []byte("linters:\n  - name: a\n    comand: a\n"), 0644))
+	_, err = LoadCustomLinters(yamlPath)
+	require.Error(err, "unknown fields must be reported")
+
+	_, err = LoadCustomLinters(filepath.Join(dir, "missing.yml"))
+	require.Error(err)
+}
+
+func TestSetCustomLinters(t *testing.T) {
+	require := require.New(t)
+	defer withCustomLinters(t, golintLinter, jsonLinter)()
+
+	require.Equal([]string{"dupl", "gosec", "gofmt", "goimports", "lll", "misspell", "gocyclo",
+		"golint", "jsonlint"}, knownLinters())
+	require.True(isKnownLinter("golint"))
+	require.False(isDefaultLinter("golint"))
+	require.Contains(linterOptions("golint"), "minConfidence")
+	require.Contains(linterOptions("lll"), "maxLen")
+
+	err := SetCustomLinters([]CustomLinter{
+		{Name: "lll", Command: "lll {path}", Pattern: golintLinter.Pattern},
+		{Name: "a b", Command: "a {path}", Pattern: `(?P<path>.*):(?P<line>\d+)`},
+		{Name: "c", Command: "c", Pattern: "(", Severity: "info"},
+		{Name: "d", Command: "d {path}", Gometalinter: true,
+			JSON: &JSONMapping{}},
+		{Name: "d", Command: "d {path}", Pattern: "x", JSON: &JSONMapping{Path: "p", Line: "l"},
+			Options: map[string]CustomOption{
+				"enabled": {Type: "bool", Argument: "-e"},
+				"level":   {Type: "float", Argument: "-l={value}"},
+				"min":     {Type: "int", Argument: "-m"},
+				"max":     {Type: "int", Argument: "-m={value}", Default: "many"},
+			}},
+	})
+	require.EqualError(err, "wrong custom linters: "+
+		"linters[0].name: linter \"lll\" is already defined; "+
+		"linters[1].name: must consist of letters, digits, '_' and '-'; "+
+		"linters[1].pattern: must have \"message\" named group; "+
+		"linters[2].command: must contain {path}; "+
+		"linters[2].pattern: error parsing regexp: missing closing ): `(`; "+
+		"linters[2].severity: must be one of \"warning\", \"error\"; "+
+		"linters[3].command: must not contain {path}, gometalinter appends paths; "+
+		"linters[3].json: is not supported by gometalinter; "+
+		"linters[3].json.path: is required; "+
+		"linters[3].json.line: is required; "+
+		"linters[3].json.message: is required; "+
+		"linters[4].name: duplicate linter \"d\"; "+
+		"linters[4].command: must contain {args} to pass options; "+
+		"linters[4].json: only one of pattern and json can be set; "+
+		"linters[4].pattern: must have \"path\" named group; "+
+		"linters[4].pattern: must have \"line\" named group; "+
+		"linters[4].pattern: must have \"message\" named group; "+
+		"linters[4].json.message: is required; "+
+		"linters[4].options.enabled: option of every linter can't be redefined; "+
+		"linters[4].options.level.type: must be one of \"bool\", \"int\", \"number\", \"string\"; "+
+		"linters[4].options.max.default: must be an integer; "+
+		"linters[4].options.min.argument: must contain {value}")
+
+	require.Equal([]string{"golint", "jsonlint"}, customLinterNames(),
+		"linters must not be changed if the new ones are wrong")
+}
+
+func TestCustomLinterArguments(t *testing.T) {
+	require := require.New(t)
+	defer withCustomLinters(t, golintLinter, jsonLinter)()
+
+	settings := readLinterSettings(logger, pb.ToValue([]map[string]interface{}{
+		{"name": "lll", "maxLen": 100},
+		{"name": "jsonlint", "strict": true, "depth": "3", "unknown": 1},
+	}), nil)
+
+	require.Equal([]string{
+		"--line-length=100",
+		`--linter=golint:golint -min_confidence=0.8:^(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+): (?P<message>.*)$`,
+		"--enable=golint",
+		"--severity=golint:warning",
+	}, settings.arguments(logger))
+
+	commands := settings.commands(logger)
+	require.Len(commands, 1)
+	require.Equal("jsonlint", commands[0].linter.Name)
+	require.Equal([]string{"--depth=3", "--strict"}, commands[0].args)
+	require.Equal([]string{"jsonlint", "--depth=3", "--strict", "--dir={path}"},
+		commands[0].linter.commandLine(commands[0].args))
+
+	settings = readLinterSettings(logger, pb.ToValue([]map[string]interface{}{
+		{"name": "golint", "minConfidence": 0.5},
+		{"name": "jsonlint", "enabled": false, "strict": false},
+	}), nil)
+	require.Equal([]string{
+		`--linter=golint:golint -min_confidence=0.5:^(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+): (?P<message>.*)$`,
+		"--enable=golint",
+		"--severity=golint:warning",
+	}, settings.arguments(logger))
+	require.Empty(settings.commands(logger))
+
+	require.Empty(ValidateConfig(*pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{{"name": "golint", "minConfidence": "0.3"}},
+	})))
+	require.Equal([]ConfigError{{"linters[0].strict", "must be a boolean"}},
+		ValidateConfig(*pb.ToStruct(map[string]interface{}{
+			"linters": []map[string]interface{}{{"name": "jsonlint", "strict": "yes"}},
+		})))
+}
+
+func TestCustomLinterStringOptions(t *testing.T) {
+	require := require.New(t)
+
+	tagsLinter := CustomLinter{
+		Name:         "tagslint",
+		Command:      "tagslint {args}",
+		Pattern:      golintLinter.Pattern,
+		Gometalinter: true,
+		Options: map[string]CustomOption{
+			"tags": {Type: "string", Argument: "-tags={value}"},
+		},
+	}
+	directLinter := CustomLinter{
+		Name:    "directlint",
+		Command: "directlint {args} {path}",
+		Pattern: golintLinter.Pattern,
+		Options: map[string]CustomOption{
+			"title": {Type: "string", Argument: "--title={value}"},
+		},
+	}
+	defer withCustomLinters(t, tagsLinter, directLinter)()
+
+	settings := readLinterSettings(logger, pb.ToValue([]map[string]interface{}{
+		{"name": "tagslint", "tags": "integration,linux"},
+		{"name": "directlint", "title": "two words"},
+	}), nil)
+	require.Equal(`--linter=tagslint:tagslint -tags=integration,linux:`+golintLinter.Pattern,
+		settings.arguments(logger)[0])
+	require.Equal([]string{"--title=two words"}, settings.commands(logger)[0].args,
+		"values are passed to linters run directly as single arguments")
+
+	for _, value := range []string{"x -exec=rm", "x:y", `x"`, "x\ty"} {
+		conf := *pb.ToStruct(map[string]interface{}{
+			"linters": []map[string]interface{}{{"name": "tagslint", "tags": value}},
+		})
+		require.Equal([]ConfigError{{"linters[0].tags",
+			`must consist of letters, digits and "_.,=+@%/~-" to be passed to gometalinter`}},
+			ValidateConfig(conf), "value: %q", value)
+
+		settings := readLinterSettings(logger, conf.Fields["linters"], nil)
+		require.Equal(`--linter=tagslint:tagslint:`+golintLinter.Pattern, settings.arguments(logger)[0],
+			"wrong value %q must not be passed", value)
+	}
+
+	tagsLinter.Options = map[string]CustomOption{
+		"tags": {Type: "string", Argument: "-tags={value}", Default: "a b"},
+	}
+	require.EqualError(SetCustomLinters([]CustomLinter{tagsLinter}), "wrong custom linters: "+
+		`linters[0].options.tags.default: must consist of letters, digits and "_.,=+@%/~-" to be passed to gometalinter`)
+}
+
+func TestCustomLinterParse(t *testing.T) {
+	require := require.New(t)
+
+	comments := golintLinter.parseLines("/tmp/ws", []byte(
+		"a.go:1:2: exported X should have comment\n"+
+			"garbage\n"+
+			"/abs/b.go:3:1: error strings should not be capitalized\n"))
+	require.Equal([]Comment{
+		NewComment("warning", "/tmp/ws/a.go", 1, 2, " exported X should have comment (golint)"),
+		NewComment("warning", "/abs/b.go", 3, 1, " error strings should not be capitalized (golint)"),
+	}, comments)
+
+	comments, err := jsonLinter.parseJSON("/tmp/ws", []byte(`
+		{"pos": {"file": "a.go", "line": 1, "col": 2}, "text": "bad", "level": "warning"}
+		[{"pos": {"file": "b.go", "line": "3"}, "text": "worse"}]`))
+	require.NoError(err)
+	require.Equal([]Comment{
+		NewComment("warning", "/tmp/ws/a.go", 1, 2, " bad (jsonlint)"),
+		NewComment("error", "/tmp/ws/b.go", 3, 0, " worse (jsonlint)"),
+	}, comments)
+
+	comments, err = jsonLinter.parseJSON("/tmp/ws", []byte(`{"pos": {"file": "a.go", "line": 1}, "text": "bad"} {`))
+	require.Error(err)
+	require.Len(comments, 1)
+
+	_, err = jsonLinter.parseJSON("/tmp/ws", []byte(`{"pos": {"file": "a.go"}, "text": "bad"}`))
+	require.EqualError(err, `no "pos.line" integer field in JSON output`)
+}
@@ -0,0 +1,157 @@
+// Package datatest wraps the in-memory localdata.DataClient for tests.
+// It records requests and can make calls and streams fail.
+package datatest
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/src-d/lookout-gometalint-analyzer/localdata"
+	"google.golang.org/grpc"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// DataClient implements pb.DataClient serving in-memory changes and files
+// with localdata.DataClient and recording the requests it receives.
+type DataClient struct {
+	*localdata.DataClient
+	// ChangesFailures are failures of consecutive GetChanges calls,
+	// the calls after them succeed
+	ChangesFailures []Failure
+
+	mu              sync.Mutex
+	changesRequests []*pb.ChangesRequest
+	filesRequests   []*pb.FilesRequest
+}
+
+var _ pb.DataClient = &DataClient{}
+
+// Failure makes a call or a stream fail
+type Failure struct {
+	// After is the number of items sent before the stream fails,
+	// negative to fail the call itself
+	After int
+	Err   error
+}
+
+// NewDataClient returns a DataClient serving the given changes
+func NewDataClient(changes ...*pb.Change) *DataClient {
+	return &DataClient{DataClient: localdata.NewDataClient(changes...)}
+}
+
+// GetChanges returns a stream of changes matching the request
+func (c *DataClient) GetChanges(ctx context.Context, in *pb.ChangesRequest,
+	opts ...grpc.CallOption) (pb.Data_GetChangesClient, error) {
+
+	c.mu.Lock()
+	call := len(c.changesRequests)
+	c.changesRequests = append(c.changesRequests, in)
+	c.mu.Unlock()
+
+	var failure *Failure
+	if call < len(c.ChangesFailures) {
+		failure = &c.ChangesFailures[call]
+		if failure.After < 0 {
+			return nil, failure.Err
+		}
+	}
+
+	stream, err := c.DataClient.GetChanges(ctx, in, opts...)
+	if err != nil || failure == nil {
+		return stream, err
+	}
+
+	return &failingChangesClient{
+		Data_GetChangesClient: stream,
+		left:                  failure.After,
+		err:                   failure.Err,
+	}, nil
+}
+
+// GetFiles returns a stream of files matching the request
+func (c *DataClient) GetFiles(ctx context.Context, in *pb.FilesRequest,
+	opts ...grpc.CallOption) (pb.Data_GetFilesClient, error) {
+
+	c.mu.Lock()
+	c.filesRequests = append(c.filesRequests, in)
+	c.mu.Unlock()
+
+	return c.DataClient.GetFiles(ctx, in, opts...)
+}
+
+// ChangesRequests returns all requests received by GetChanges
+func (c *DataClient) ChangesRequests() []*pb.ChangesRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*pb.ChangesRequest(nil), c.changesRequests...)
+}
+
+// FilesRequests returns all requests received by GetFiles
+func (c *DataClient) FilesRequests() []*pb.FilesRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*pb.FilesRequest(nil), c.filesRequests...)
+}
+
+// failingChangesClient fails the stream after left changes
+// unless it ends before
+type failingChangesClient struct {
+	pb.Data_GetChangesClient
+	left int
+	err  error
+}
+
+// Recv returns the next change or the failure after left changes
+func (s *failingChangesClient) Recv() (*pb.Change, error) {
+	change, err := s.Data_GetChangesClient.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.left == 0 {
+		return nil, s.err
+	}
+
+	s.left--
+	return change, nil
+}
+
+// File returns a regular file with the given path and content
+func File(filePath string, content string) *pb.File {
+	return &pb.File{
+		Path:     filePath,
+		Mode:     0100644,
+		Content:  []byte(content),
+		Language: localdata.LanguageByPath(filePath),
+	}
+}
+
+// FileFromDisk reads a file from dir and returns it under the given path.
+// The path is slash separated and relative to dir.
+func FileFromDisk(dir, filePath string) (*pb.File, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(filePath)))
+	if err != nil {
+		return nil, err
+	}
+
+	return File(filePath, string(content)), nil
+}
+
+// AddedFromDisk returns changes adding given files read from dir
+func AddedFromDisk(dir string, paths ...string) ([]*pb.Change, error) {
+	var changes []*pb.Change
+	for _, p := range paths {
+		f, err := FileFromDisk(dir, p)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, &pb.Change{Head: f})
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,93 @@
+package datatest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func changedPaths(t *testing.T, stream pb.Data_GetChangesClient) []string {
+	var paths []string
+	for {
+		change, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+		f := change.Head
+		if f == nil {
+			f = change.Base
+		}
+
+		paths = append(paths, f.Path)
+	}
+
+	return paths
+}
+
+func TestGetChangesFailures(t *testing.T) {
+	require := require.New(t)
+
+	errCall := errors.New("call failed")
+	errStream := errors.New("stream failed")
+	client := NewDataClient(
+		&pb.Change{Head: File("a.go", "package a\n")},
+		&pb.Change{Head: File("b.go", "package b\n")},
+	)
+	client.ChangesFailures = []Failure{{After: -1, Err: errCall}, {After: 1, Err: errStream}}
+
+	ctx := context.Background()
+	_, err := client.GetChanges(ctx, &pb.ChangesRequest{})
+	require.Equal(errCall, err)
+
+	stream, err := client.GetChanges(ctx, &pb.ChangesRequest{})
+	require.NoError(err)
+	change, err := stream.Recv()
+	require.NoError(err)
+	require.Equal("a.go", change.Head.Path)
+	_, err = stream.Recv()
+	require.Equal(errStream, err)
+
+	stream, err = client.GetChanges(ctx, &pb.ChangesRequest{})
+	require.NoError(err)
+	require.Equal([]string{"a.go", "b.go"}, changedPaths(t, stream))
+}
+
+func TestRequests(t *testing.T) {
+	require := require.New(t)
+
+	client := NewDataClient(&pb.Change{Head: File("main.go", "package main\n")})
+	client.Files = []*pb.File{File("main.go", "package main\n")}
+
+	ctx := context.Background()
+	req := &pb.ChangesRequest{IncludeLanguages: []string{"go"}}
+	stream, err := client.GetChanges(ctx, req)
+	require.NoError(err)
+	require.Equal([]string{"main.go"}, changedPaths(t, stream))
+
+	_, err = client.GetFiles(ctx, &pb.FilesRequest{})
+	require.NoError(err)
+
+	require.Equal([]*pb.ChangesRequest{req}, client.ChangesRequests())
+	require.Len(client.FilesRequests(), 1)
+}
+
+func TestAddedFromDisk(t *testing.T) {
+	require := require.New(t)
+
+	changes, err := AddedFromDisk("../_fixtures", "gofmt_test.go")
+	require.NoError(err)
+	require.Len(changes, 1)
+	require.Nil(changes[0].Base)
+	require.Equal("gofmt_test.go", changes[0].Head.Path)
+	require.Equal("Go", changes[0].Head.Language)
+	require.Equal("package test\nfunc test() { if nil {} }", string(changes[0].Head.Content))
+
+	_, err = AddedFromDisk("../_fixtures", "missing.go")
+	require.Error(err)
+}
@@ -0,0 +1,142 @@
+package gometalint
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// lineMapping maps numbers of unchanged lines of the head content
+// to their numbers in the base content, lines are numbered from 1
+func lineMapping(base, head []byte) map[int32]int32 {
+	m := difflib.NewMatcher(splitLines(base), splitLines(head))
+	mapping := make(map[int32]int32)
+	for _, block := range m.GetMatchingBlocks() {
+		for i := 0; i < block.Size; i++ {
+			mapping[int32(block.B+i+1)] = int32(block.A + i + 1)
+		}
+	}
+
+	return mapping
+}
+
+func splitLines(content []byte) []string {
+	return strings.Split(string(content), "\n")
+}
+
+// baseIndex keeps base revisions of changed files
+// to find where lines of the head revisions come from
+type baseIndex struct {
+	// files maps head paths to the changes
+	files    map[string]indexedFile
+	mappings map[string]map[int32]int32
+}
+
+// indexedFile is a changed file, base is nil for new files
+type indexedFile struct {
+	base, head []byte
+}
+
+func newBaseIndex() *baseIndex {
+	return &baseIndex{
+		files:    make(map[string]indexedFile),
+		mappings: make(map[string]map[int32]int32),
+	}
+}
+
+// add records the change, changes without head are ignored
+func (b *baseIndex) add(change *pb.Change) {
+	if change.Head == nil {
+		return
+	}
+
+	// paths of comments are in canonical form
+	path, err := cleanPath(change.Head.Path)
+	if err != nil {
+		return
+	}
+
+	var base []byte
+	if change.Base != nil {
+		base = change.Base.Content
+		if base == nil {
+			base = []byte{}
+		}
+	}
+
+	b.files[path] = indexedFile{base: base, head: change.Head.Content}
+}
+
+// baseLine returns number of the line in the base revision the line of
+// the head revision comes from, false if the line is new. Line 0 refers to
+// the whole file, it's new only if the file is new.
+func (b *baseIndex) baseLine(path string, line int32) (int32, bool) {
+	f, ok := b.files[path]
+	if !ok || f.base == nil {
+		return 0, false
+	}
+
+	if line == 0 {
+		return 0, true
+	}
+
+	baseLine, ok := b.mapping(path, f)[line]
+	return baseLine, ok
+}
+
+// baseHunk returns the range of lines of the base revision replaced by the
+// hunk the new line of the head revision is in, both ends are exclusive.
+// It returns false if the line comes from the base revision or the file is new.
+func (b *baseIndex) baseHunk(path string, line int32) (from, to int32, ok bool) {
+	f, ok := b.files[path]
+	if !ok || f.base == nil || line == 0 {
+		return 0, 0, false
+	}
+
+	mapping := b.mapping(path, f)
+	if _, ok := mapping[line]; ok {
+		return 0, 0, false
+	}
+
+	// unchanged lines keep their order, so the closest ones
+	// around the line have the closest base lines
+	from, to = 0, math.MaxInt32
+	for headLine, baseLine := range mapping {
+		if headLine < line && baseLine > from {
+			from = baseLine
+		}
+
+		if headLine > line && baseLine < to {
+			to = baseLine
+		}
+	}
+
+	return from, to, true
+}
+
+// mapping returns the cached line mapping of the file
+func (b *baseIndex) mapping(path string, f indexedFile) map[int32]int32 {
+	mapping, ok := b.mappings[path]
+	if !ok {
+		mapping = lineMapping(f.base, f.head)
+		b.mappings[path] = mapping
+	}
+
+	return mapping
+}
+
+// splitByBase splits issues into new ones and ones on lines
+// coming from the base revision
+func splitByBase(issues []Comment, bases *baseIndex) (newIssues, oldIssues []Comment) {
+	for _, issue := range issues {
+		if _, ok := bases.baseLine(issue.file, issue.lino); ok {
+			oldIssues = append(oldIssues, issue)
+		} else {
+			newIssues = append(newIssues, issue)
+		}
+	}
+
+	return newIssues, oldIssues
+}
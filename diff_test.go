@@ -0,0 +1,52 @@
+package gometalint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestLineMapping(t *testing.T) {
+	base := "package a\n\nfunc a() {}\n\nfunc b() {}\n"
+	head := "package a\n\nimport \"fmt\"\n\nfunc a() {}\n\nfunc c() {}\n"
+
+	require.Equal(t, map[int32]int32{
+		1: 1,
+		4: 2,
+		5: 3,
+		6: 4,
+		8: 6,
+	}, lineMapping([]byte(base), []byte(head)))
+}
+
+func TestBaseIndex(t *testing.T) {
+	require := require.New(t)
+
+	b := newBaseIndex()
+	b.add(&pb.Change{
+		Base: &pb.File{Path: "a.go", Content: []byte("package a\n\nvar a = 1\n")},
+		Head: &pb.File{Path: "a.go", Content: []byte("package a\n\nvar b = 2\n\nvar a = 1\n")},
+	})
+	b.add(&pb.Change{Head: &pb.File{Path: "./new.go", Content: []byte("package a\n")}})
+	b.add(&pb.Change{Base: &pb.File{Path: "deleted.go"}})
+
+	for _, tt := range []struct {
+		path string
+		line int32
+		base int32
+		ok   bool
+	}{
+		{"a.go", 1, 1, true},
+		{"a.go", 3, 0, false},
+		{"a.go", 5, 3, true},
+		{"a.go", 0, 0, true},
+		{"new.go", 1, 0, false},
+		{"new.go", 0, 0, false},
+		{"deleted.go", 1, 0, false},
+	} {
+		line, ok := b.baseLine(tt.path, tt.line)
+		require.Equal(tt.ok, ok, "%s:%d", tt.path, tt.line)
+		require.Equal(tt.base, line, "%s:%d", tt.path, tt.line)
+	}
+}
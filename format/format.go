@@ -0,0 +1,150 @@
+// Package format writes gometalint comments in formats understood by
+// other tools: editors, CI systems and code-scanning dashboards.
+package format
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
+
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// Formatter writes comments to w
+type Formatter func(w io.Writer, comments []gometalint.Comment) error
+
+// formatters is a map of supported formats
+var formatters = map[string]Formatter{
+	"text":       Text,
+	"json":       JSON,
+	"checkstyle": Checkstyle,
+	"sarif":      SARIF,
+}
+
+// Names returns sorted names of supported formats
+func Names() []string {
+	var names []string
+	for name := range formatters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Get returns formatter by its name
+func Get(name string) (Formatter, error) {
+	f, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, supported formats: %s",
+			name, strings.Join(Names(), ", "))
+	}
+
+	return f, nil
+}
+
+// Text writes comments in gometalinter format:
+// path:line:[column]:severity: message (linter)
+func Text(w io.Writer, comments []gometalint.Comment) error {
+	for _, c := range comments {
+		col := ""
+		if c.Column() > 0 {
+			col = fmt.Sprint(c.Column())
+		}
+
+		_, err := fmt.Fprintf(w, "%s:%d:%s:%s: %s\n",
+			c.File(), c.Line(), col, c.Level(), strings.TrimSpace(c.Text()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonComment is pb.Comment with the fingerprint of the issue
+type jsonComment struct {
+	*pb.Comment
+	Fingerprint string `json:"fingerprint"`
+}
+
+// JSON writes comments as JSON lines of pb.Comment with fingerprints
+func JSON(w io.Writer, comments []gometalint.Comment) error {
+	enc := json.NewEncoder(w)
+	fingerprints := gometalint.Fingerprints(comments)
+	for i, c := range comments {
+		err := enc.Encode(jsonComment{
+			Comment: &pb.Comment{
+				File: filepath.ToSlash(c.File()),
+				Line: c.Line(),
+				Text: strings.TrimSpace(c.Text()),
+			},
+			Fingerprint: fingerprints[i],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int32  `xml:"line,attr"`
+	Column   int32  `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// Checkstyle writes comments as checkstyle XML report
+func Checkstyle(w io.Writer, comments []gometalint.Comment) error {
+	report := checkstyleReport{Version: "5.0"}
+	files := make(map[string]int)
+	for _, c := range comments {
+		i, ok := files[c.File()]
+		if !ok {
+			i = len(report.Files)
+			files[c.File()] = i
+			report.Files = append(report.Files, checkstyleFile{Name: c.File()})
+		}
+
+		report.Files[i].Errors = append(report.Files[i].Errors, checkstyleError{
+			Line:     c.Line(),
+			Column:   c.Column(),
+			Severity: c.Level(),
+			Message:  c.Message(),
+			Source:   c.Linter(),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
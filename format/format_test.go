@@ -0,0 +1,95 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
+
+	"github.com/stretchr/testify/require"
+)
+
+var comments = []gometalint.Comment{
+	gometalint.NewComment("warning", "a/b.go", 3, 0, " line is 120 characters (lll)"),
+	gometalint.NewComment("error", "a/b.go", 7, 9, ` "langauge" is a misspelling of "language" (misspell)`),
+	gometalint.NewComment("warning", "c.go", 0, 0, " file is not gofmted with -s (gofmt)"),
+}
+
+func format(t *testing.T, name string, comments []gometalint.Comment) string {
+	f, err := Get(name)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, f(&buf, comments))
+	return buf.String()
+}
+
+func TestGet(t *testing.T) {
+	require.Equal(t, []string{"checkstyle", "json", "sarif", "text"}, Names())
+
+	_, err := Get("unknown")
+	require.Error(t, err)
+}
+
+func TestText(t *testing.T) {
+	require.Equal(t, `a/b.go:3::warning: line is 120 characters (lll)
+a/b.go:7:9:error: "langauge" is a misspelling of "language" (misspell)
+c.go:0::warning: file is not gofmted with -s (gofmt)
+`, format(t, "text", comments))
+
+	require.Empty(t, format(t, "text", nil))
+}
+
+func TestJSON(t *testing.T) {
+	fps := gometalint.Fingerprints(comments)
+	require.Equal(t, fmt.Sprintf(`{"file":"a/b.go","line":3,"text":"line is 120 characters (lll)","fingerprint":"%s"}
+{"file":"a/b.go","line":7,"text":"\"langauge\" is a misspelling of \"language\" (misspell)","fingerprint":"%s"}
+{"file":"c.go","text":"file is not gofmted with -s (gofmt)","fingerprint":"%s"}
+`, fps[0], fps[1], fps[2]), format(t, "json", comments))
+}
+
+func TestCheckstyle(t *testing.T) {
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="5.0">
+  <file name="a/b.go">
+    <error line="3" column="0" severity="warning" message="line is 120 characters" source="lll"></error>
+    <error line="7" column="9" severity="error" message="&#34;langauge&#34; is a misspelling of &#34;language&#34;" source="misspell"></error>
+  </file>
+  <file name="c.go">
+    <error line="0" column="0" severity="warning" message="file is not gofmted with -s" source="gofmt"></error>
+  </file>
+</checkstyle>
+`, format(t, "checkstyle", comments))
+}
+
+func TestSARIF(t *testing.T) {
+	require := require.New(t)
+
+	var log sarifLog
+	require.NoError(json.Unmarshal([]byte(format(t, "sarif", comments)), &log))
+	require.Equal("2.1.0", log.Version)
+	require.Len(log.Runs, 1)
+
+	run := log.Runs[0]
+	require.Equal("gometalinter", run.Tool.Driver.Name)
+	require.Equal([]sarifRule{{ID: "lll"}, {ID: "misspell"}, {ID: "gofmt"}}, run.Tool.Driver.Rules)
+	require.Len(run.Results, 3)
+
+	r := run.Results[1]
+	require.Equal("misspell", r.RuleID)
+	require.Equal(1, *r.RuleIndex)
+	require.Equal("error", r.Level)
+	require.Equal(`"langauge" is a misspelling of "language"`, r.Message.Text)
+	require.Equal("a/b.go", r.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	require.Equal(&sarifRegion{StartLine: 7, StartColumn: 9}, r.Locations[0].PhysicalLocation.Region)
+	require.Equal(map[string]string{"gometalintFingerprint/v1": gometalint.Fingerprints(comments)[1]},
+		r.PartialFingerprints)
+
+	require.Nil(run.Results[2].Locations[0].PhysicalLocation.Region,
+		"region must be omitted for unknown line")
+
+	require.NoError(json.Unmarshal([]byte(format(t, "sarif", nil)), &log))
+	require.NotNil(log.Runs[0].Results, "results must be an empty array")
+}
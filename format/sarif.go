@@ -0,0 +1,143 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	gometalint "github.com/src-d/lookout-gometalint-analyzer"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://schemastore.azurewebsites.net/schemas/json/sarif-2.1.0-rtm.4.json"
+	// fingerprintName is the key of gometalint.Fingerprints in partial fingerprints
+	fingerprintName = "gometalintFingerprint/v1"
+)
+
+// SARIF log structures, only the subset used by the analyzer.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	RuleIndex *int            `json:"ruleIndex,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	// PartialFingerprints identify the result across revisions
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int32 `json:"startLine"`
+	StartColumn int32 `json:"startColumn,omitempty"`
+}
+
+// sarifLevel converts gometalint severity to SARIF level
+func sarifLevel(severity string) string {
+	switch severity {
+	case "error":
+		return "error"
+	case "warning":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// SARIF writes comments as SARIF 2.1.0 log with a single run
+func SARIF(w io.Writer, comments []gometalint.Comment) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "gometalinter",
+			InformationURI: "https://github.com/alecthomas/gometalinter",
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]int)
+	fingerprints := gometalint.Fingerprints(comments)
+	for i, c := range comments {
+		result := sarifResult{
+			Level:   sarifLevel(c.Level()),
+			Message: sarifMessage{Text: c.Message()},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(c.File())},
+				},
+			}},
+			PartialFingerprints: map[string]string{fingerprintName: fingerprints[i]},
+		}
+
+		if c.Line() > 0 {
+			result.Locations[0].PhysicalLocation.Region = &sarifRegion{
+				StartLine:   c.Line(),
+				StartColumn: c.Column(),
+			}
+		}
+
+		if linter := c.Linter(); linter != "" {
+			i, ok := rules[linter]
+			if !ok {
+				i = len(run.Tool.Driver.Rules)
+				rules[linter] = i
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: linter})
+			}
+
+			result.RuleID = linter
+			result.RuleIndex = &i
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,116 @@
+package gometalint
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// defaultGeneratedGlobs are names of files that are always generated
+var defaultGeneratedGlobs = []string{"*.pb.go", "zz_generated*"}
+
+// generatedHeader is the comment marking generated Go files,
+// see https://golang.org/s/generatedcode
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// generatedFilter decides which files are generated and must not be linted
+type generatedFilter struct {
+	skip  bool
+	globs []string
+}
+
+// isGenerated returns true if the file must be skipped as generated
+func (f generatedFilter) isGenerated(file *pb.File) bool {
+	if !f.skip {
+		return false
+	}
+
+	return matchGlobs(file.Path, f.globs) || hasGeneratedHeader(file.Content)
+}
+
+// matchGlobs returns true if the slash-separated path matches any glob.
+// Globs without '/' are matched against the file name only.
+func matchGlobs(p string, globs []string) bool {
+	for _, glob := range globs {
+		name := p
+		if !strings.Contains(glob, "/") {
+			name = path.Base(p)
+		}
+
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasGeneratedHeader returns true if the generated code comment
+// appears before the package clause
+func hasGeneratedHeader(content []byte) bool {
+	s := bufio.NewScanner(bytes.NewReader(content))
+	s.Buffer(nil, len(content)+1)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if generatedHeader.MatchString(line) {
+			return true
+		}
+
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+
+	return false
+}
+
+// generatedConfig reads generated files options from the configuration:
+// "skipGenerated" to disable skipping and "generated" with additional globs
+func generatedConfig(logger log.Logger, s types.Struct) generatedFilter {
+	f := generatedFilter{
+		skip:  true,
+		globs: append([]string(nil), defaultGeneratedGlobs...),
+	}
+
+	config := s.GetFields()
+	if config == nil {
+		return f
+	}
+
+	if v, ok := config["skipGenerated"]; ok && v != nil {
+		if b, ok := v.GetKind().(*types.Value_BoolValue); ok {
+			f.skip = b.BoolValue
+		} else {
+			logger.Warningf("wrong type for skipGenerated argument")
+		}
+	}
+
+	v, ok := config["generated"]
+	if !ok || v == nil {
+		return f
+	}
+
+	list := v.GetListValue()
+	if list == nil {
+		logger.Warningf("wrong type for generated argument")
+		return f
+	}
+
+	for _, glob := range list.GetValues() {
+		g := glob.GetStringValue()
+		if _, err := path.Match(g, ""); g == "" || err != nil {
+			logger.Warningf("wrong glob %q in generated argument", g)
+			continue
+		}
+
+		f.globs = append(f.globs, g)
+	}
+
+	return f
+}
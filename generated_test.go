@@ -0,0 +1,60 @@
+package gometalint
+
+import (
+	"testing"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+var generatedTests = []struct {
+	path      string
+	content   string
+	generated bool
+}{
+	{"a.go", "package a\n", false},
+	{"api/a.pb.go", "package api\n", true},
+	{"pkg/zz_generated.deepcopy.go", "package pkg\n", true},
+	{"a.go", "// Code generated by stringer. DO NOT EDIT.\n\npackage a\n", true},
+	{"a.go", "// Copyright\n\n// Code generated by go-bindata. DO NOT EDIT.\n// +build ignore\n\npackage a\n", true},
+	{"a.go", "package a\n\n// Code generated by stringer. DO NOT EDIT.\n", false},
+	{"a.go", "// Code generated by stringer. Feel free to edit.\npackage a\n", false},
+	{"a.go", "/*\n// Code generated by hand. DO NOT EDIT.\n*/\npackage a\n", true},
+}
+
+func TestIsGenerated(t *testing.T) {
+	f := generatedConfig(logger, types.Struct{})
+	for _, tt := range generatedTests {
+		t.Run(tt.path, func(t *testing.T) {
+			file := &pb.File{Path: tt.path, Content: []byte(tt.content)}
+			require.Equal(t, tt.generated, f.isGenerated(file), tt.content)
+		})
+	}
+}
+
+func TestGeneratedConfig(t *testing.T) {
+	require := require.New(t)
+
+	f := generatedConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"skipGenerated": false,
+	}))
+	require.False(f.isGenerated(&pb.File{Path: "a.pb.go"}))
+
+	f = generatedConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"generated": []string{"*_gen.go", "mocks/*", "[", ""},
+	}))
+	require.True(f.skip)
+	require.Equal([]string{"*.pb.go", "zz_generated*", "*_gen.go", "mocks/*"}, f.globs)
+	require.True(f.isGenerated(&pb.File{Path: "a/b/types_gen.go"}))
+	require.True(f.isGenerated(&pb.File{Path: "mocks/client.go"}))
+	require.False(f.isGenerated(&pb.File{Path: "a/mocks/client.go"}))
+	require.True(f.isGenerated(&pb.File{Path: "a.pb.go"}))
+
+	f = generatedConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"skipGenerated": "no",
+		"generated":     "*_gen.go",
+	}))
+	require.True(f.skip, "wrong values must be ignored")
+	require.Equal(defaultGeneratedGlobs, f.globs)
+}
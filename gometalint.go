@@ -3,9 +3,14 @@ package gometalint
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"fmt"
+	"os"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 
 	log "gopkg.in/src-d/go-log.v1"
 )
@@ -19,21 +24,109 @@ var (
 	}
 )
 
+// enablePrefix is the prefix of gometalint argument enabling a linter
+const enablePrefix = "--enable="
+
+// defaultLinters returns names of the linters enabled by default
+func defaultLinters() []string {
+	var linters []string
+	for _, arg := range defaultArgs {
+		if strings.HasPrefix(arg, enablePrefix) {
+			linters = append(linters, strings.TrimPrefix(arg, enablePrefix))
+		}
+	}
+
+	return linters
+}
+
+// isDefaultLinter returns true if the linter is enabled by default
+func isDefaultLinter(name string) bool {
+	return containsString(defaultLinters(), name)
+}
+
+// knownLinters returns names of the default and custom linters
+func knownLinters() []string {
+	return append(defaultLinters(), customLinterNames()...)
+}
+
+// isKnownLinter returns true if the linter is a default or custom one
+func isKnownLinter(name string) bool {
+	return containsString(knownLinters(), name)
+}
+
 // Comment as returned by gometalint
 type Comment struct {
 	level string
 	file  string
 	lino  int32
+	col   int32
 	text  string
 }
 
-// RunGometalinter execs gometalint binary \w pre-configured set of linters
+// NewComment returns a comment with the given fields
+func NewComment(level, file string, line, col int32, text string) Comment {
+	return Comment{level: level, file: file, lino: line, col: col, text: text}
+}
+
+// linterSuffix matches name of the linter gometalint appends to messages
+var linterSuffix = regexp.MustCompile(`\s*\(([\w-]+)\)$`)
+
+// Level returns severity of the comment, e.g. "warning" or "error"
+func (c Comment) Level() string { return c.level }
+
+// File returns path of the file the comment is about
+func (c Comment) File() string { return c.file }
+
+// Line returns line number of the comment, 0 if unknown
+func (c Comment) Line() int32 { return c.lino }
+
+// Column returns column number of the comment, 0 if unknown
+func (c Comment) Column() int32 { return c.col }
+
+// Text returns text of the comment as printed by gometalint
+func (c Comment) Text() string { return c.text }
+
+// Linter returns name of the linter that created the comment
+func (c Comment) Linter() string {
+	m := linterSuffix.FindStringSubmatch(c.text)
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
+
+// Message returns text of the comment without the linter name
+func (c Comment) Message() string {
+	return strings.TrimSpace(linterSuffix.ReplaceAllString(c.text, ""))
+}
+
+// RunGometalinter execs gometalint binary \w pre-configured set of linters.
+// Failures of the binary are only logged, use RunGometalinterErr to get them.
 func RunGometalinter(args []string) []Comment {
+	comments, err := RunGometalinterErr(args)
+	if err != nil {
+		log.Warningf("gometalinter failed: %s", err)
+	}
+
+	return comments
+}
+
+// RunGometalinterErr is RunGometalinter returning the error if the binary failed,
+// issues found before are returned anyway.
+func RunGometalinterErr(args []string) ([]Comment, error) {
+	return runGometalinter(context.Background(), args, nil, nil)
+}
+
+// runGometalinter is RunGometalinterErr running the binary in sandbox if it's not nil
+// with the environment variables added. Linters in the sandbox can write only
+// to the writable paths. The binary is killed if the context is done.
+func runGometalinter(ctx context.Context, args []string, sandbox *Sandbox, env []string, writable ...string) ([]Comment, error) {
 	dArgs := append([]string(nil), defaultArgs...)
 	args = append(dArgs, args...)
 	log.Debugf("Running '%s %v'\n", bin, args)
-	out, _ := exec.Command(bin, args...).Output() // nolint: gas
-	// ignoring err, as it's always not nil if anything found
+
+	out, stderr, runErr := runCommand(ctx, bin, args, sandbox, env, writable)
 
 	var comments []Comment
 	s := bufio.NewScanner(bytes.NewReader(out))
@@ -44,7 +137,7 @@ func RunGometalinter(args []string) []Comment {
 			continue
 		}
 
-		file, line, _, severity, msg := sp[0], sp[1], sp[2], sp[3], sp[4]
+		file, line, col, severity, msg := sp[0], sp[1], sp[2], sp[3], sp[4]
 		c := Comment{
 			level: severity,
 			file:  file,
@@ -57,8 +150,55 @@ func RunGometalinter(args []string) []Comment {
 		}
 
 		c.lino = int32(lino)
+		if colno, err := strconv.Atoi(col); err == nil {
+			c.col = int32(colno)
+		}
+
 		comments = append(comments, c)
 	}
 	log.Debugf("Done. %d issues found\n", len(comments))
-	return comments
+	return comments, checkRunError(bin, runErr, len(comments), stderr)
+}
+
+// runCommand runs the binary in sandbox if it's not nil with the environment
+// variables added and returns its output, the binary is killed if the context is done
+func runCommand(ctx context.Context, name string, args []string, sandbox *Sandbox, env []string,
+	writable []string) ([]byte, string, error) {
+
+	var stderr bytes.Buffer
+	if sandbox != nil {
+		out, err := sandbox.output(ctx, name, args, writable, env, &stderr)
+		return out, stderr.String(), err
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...) // nolint: gas
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	return out, stderr.String(), err
+}
+
+// checkRunError returns error if the linter binary run failed.
+// Linters exit with status 1 when any issue is found,
+// so it's an error only when nothing was found and something was printed to stderr.
+func checkRunError(name string, err error, issues int, stderr string) error {
+	if err == nil {
+		return nil
+	}
+
+	stderr = strings.TrimSpace(stderr)
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		status, ok := exitErr.Sys().(syscall.WaitStatus)
+		if ok && status.Exited() && status.ExitStatus() == 1 && (issues > 0 || stderr == "") {
+			return nil
+		}
+	}
+
+	if stderr != "" {
+		return fmt.Errorf("%s failed: %s: %s", name, err, stderr)
+	}
+
+	return fmt.Errorf("%s failed: %s", name, err)
 }
@@ -0,0 +1,98 @@
+package gometalint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const fixedOutputLinter = `#!/bin/sh
+echo 'a.go:3::warning: line is 120 characters (lll)'
+echo 'b/c.go:7:9:error: "langauge" is a misspelling of "language" (misspell)'
+echo 'not an issue'
+echo 'd.go:x:1:warning: wrong line (lll)'
+echo 'e.go:2:1:warning: message: with colons'
+`
+
+func TestRunGometalinter(t *testing.T) {
+	defer withFakeLinter(t, fixedOutputLinter)()
+
+	comments := RunGometalinter(nil)
+	require.Equal(t, []Comment{
+		{level: "warning", file: "a.go", lino: 3, text: " line is 120 characters (lll)"},
+		{level: "error", file: "b/c.go", lino: 7, col: 9, text: ` "langauge" is a misspelling of "language" (misspell)`},
+		{level: "warning", file: "e.go", lino: 2, col: 1, text: " message: with colons"},
+	}, comments)
+}
+
+func TestRunGometalinterFailed(t *testing.T) {
+	cases := []struct {
+		name   string
+		linter string
+		issues int
+		err    string
+	}{
+		{
+			name:   "issues found",
+			linter: "#!/bin/sh\necho 'a.go:1::warning: issue (lll)'\nexit 1\n",
+			issues: 1,
+		},
+		{
+			name:   "failed without issues",
+			linter: "#!/bin/sh\necho 'unknown flag' >&2\nexit 1\n",
+			err:    "unknown flag",
+		},
+		{
+			name:   "failed after issues",
+			linter: "#!/bin/sh\necho 'a.go:1::warning: issue (lll)'\nexit 2\n",
+			issues: 1,
+			err:    "exit status 2",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require := require.New(t)
+			defer withFakeLinter(t, c.linter)()
+
+			comments, err := RunGometalinterErr(nil)
+			require.Len(comments, c.issues)
+			if c.err == "" {
+				require.NoError(err)
+				return
+			}
+
+			require.Error(err)
+			require.Contains(err.Error(), c.err)
+
+			require.Equal(comments, RunGometalinter(nil), "error must be only logged")
+		})
+	}
+}
+
+func TestRunGometalinterCancel(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, "#!/bin/sh\nexec sleep 30\n")()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := runGometalinter(ctx, nil, nil, nil)
+	require.Error(err)
+	require.True(time.Since(start) < 10*time.Second, "linter must be killed when the context is done")
+}
+
+func TestCommentLinter(t *testing.T) {
+	require := require.New(t)
+
+	c := NewComment("warning", "a.go", 1, 0, " line is 120 characters (lll)")
+	require.Equal("lll", c.Linter())
+	require.Equal("line is 120 characters", c.Message())
+
+	c = NewComment("warning", "a.go", 1, 0, " message without linter")
+	require.Equal("", c.Linter())
+	require.Equal("message without linter", c.Message())
+}
@@ -0,0 +1,148 @@
+package gometalint
+
+import (
+	"context"
+	"go/build"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// defaultImportPath is the import path of repositories without go.mod
+// and with URL not known to be go-gettable
+const defaultImportPath = "repository"
+
+// goModPattern matches path of go.mod in the root of a repository
+const goModPattern = `^go\.mod$`
+
+// moduleDirective matches the module path in go.mod, quoted or not
+var moduleDirective = regexp.MustCompile(`(?m)^\s*module\s+("(?:[^"\\]|\\.)*"|\S+)`)
+
+// repoImportPath returns the import path of the root of the repository:
+// the module path of go.mod at the revision, the path made of the repository
+// URL or defaultImportPath if both are unknown
+func repoImportPath(ctx context.Context, logger log.Logger, client pb.DataClient,
+	rev *pb.ReferencePointer) string {
+
+	if p := fetchModulePath(ctx, logger, client, rev); p != "" {
+		return p
+	}
+
+	if p := urlImportPath(rev.InternalRepositoryURL); p != "" {
+		return p
+	}
+
+	return defaultImportPath
+}
+
+// fetchModulePath reads the module path from go.mod of the repository
+// at the revision, empty string is returned if there is no such file
+// or it can't be read
+func fetchModulePath(ctx context.Context, logger log.Logger, client pb.DataClient,
+	rev *pb.ReferencePointer) string {
+
+	stream, err := client.GetFiles(ctx, &pb.FilesRequest{
+		Revision:       rev,
+		IncludePattern: goModPattern,
+		WantContents:   true,
+	})
+	if err != nil {
+		logger.Warningf("failed to get go.mod from DataService: %s", err)
+		return ""
+	}
+
+	var modulePath string
+	for {
+		file, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			logger.Warningf("failed to get go.mod from DataService: %s", err)
+			return ""
+		}
+
+		if file.Path != "go.mod" {
+			continue
+		}
+
+		modulePath = parseModulePath(file.Content)
+		if modulePath == "" {
+			logger.Warningf("no valid module path in go.mod")
+		}
+	}
+
+	return modulePath
+}
+
+// importPathSettings returns the default settings of linters depending
+// on the import path: goimports groups imports of the repository separately
+func importPathSettings(importPath string) linterSettings {
+	if importPath == defaultImportPath {
+		return nil
+	}
+
+	return linterSettings{
+		"goimports": {"localPrefix": pb.ToValue(importPath)},
+	}
+}
+
+// parseModulePath returns the module path of go.mod content,
+// empty string if there is no valid one
+func parseModulePath(content []byte) string {
+	m := moduleDirective.FindSubmatch(content)
+	if m == nil {
+		return ""
+	}
+
+	p := string(m[1])
+	if unquoted, err := strconv.Unquote(p); err == nil {
+		p = unquoted
+	}
+
+	if !isValidImportPath(p) {
+		return ""
+	}
+
+	return p
+}
+
+// urlImportPath returns the import path of the repository with the URL,
+// e.g. "github.com/src-d/lookout", empty string if the URL is not supported
+func urlImportPath(url string) string {
+	info, err := pb.ParseRepositoryInfo(url)
+	if err != nil || info.Host == "" {
+		return ""
+	}
+
+	p := info.Host + "/" + info.FullName
+	if !isValidImportPath(p) {
+		return ""
+	}
+
+	return p
+}
+
+// isValidImportPath returns true if the import path can be used
+// as a relative path in the workspace
+func isValidImportPath(p string) bool {
+	clean, err := cleanPath(p)
+	return err == nil && clean == p
+}
+
+// env returns environment variables of linters, the packages saved to
+// the workspace are resolved in GOPATH mode without network access.
+// Packages of the analyzer GOPATH are available as well.
+func (w *workspace) env() []string {
+	gopath := w.dir
+	if build.Default.GOPATH != "" {
+		gopath += string(filepath.ListSeparator) + build.Default.GOPATH
+	}
+
+	return []string{"GOPATH=" + gopath, "GO111MODULE=off", "GOFLAGS="}
+}
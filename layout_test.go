@@ -0,0 +1,56 @@
+package gometalint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/lookout-gometalint-analyzer/datatest"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestParseModulePath(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("github.com/src-d/lookout", parseModulePath([]byte("module github.com/src-d/lookout\n")))
+	require.Equal("gopkg.in/src-d/lookout-sdk.v0", parseModulePath([]byte(
+		"// comment\n\nmodule \"gopkg.in/src-d/lookout-sdk.v0\" // sdk\n\nrequire (\n)\n")))
+	require.Empty(parseModulePath([]byte("require github.com/a/b v1.0.0\n")))
+	require.Empty(parseModulePath([]byte("module ../escape\n")))
+	require.Empty(parseModulePath([]byte("module /abs\n")))
+}
+
+func TestURLImportPath(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("github.com/src-d/lookout", urlImportPath("https://github.com/src-d/lookout.git"))
+	require.Equal("gitlab.com/a/b", urlImportPath("gitlab.com/a/b"))
+	require.Empty(urlImportPath("file:///home/user/repo"))
+	require.Empty(urlImportPath("https://example.com/a/b"))
+}
+
+func TestRepoImportPath(t *testing.T) {
+	require := require.New(t)
+
+	head := &pb.ReferencePointer{InternalRepositoryURL: "https://github.com/src-d/lookout", Hash: "head"}
+	client := datatest.NewDataClient()
+	require.Equal("github.com/src-d/lookout", repoImportPath(context.Background(), logger, client, head))
+
+	reqs := client.FilesRequests()
+	require.Len(reqs, 1)
+	require.Equal(head, reqs[0].Revision)
+	require.Equal(goModPattern, reqs[0].IncludePattern)
+
+	client.Files = []*pb.File{
+		datatest.File("sub/go.mod", "module example.com/sub\n"),
+		datatest.File("go.mod", "module example.com/lookout\n"),
+	}
+	require.Equal("example.com/lookout", repoImportPath(context.Background(), logger, client, head))
+
+	client.Files = []*pb.File{datatest.File("go.mod", "go 1.12\n")}
+	require.Equal("github.com/src-d/lookout", repoImportPath(context.Background(), logger, client, head))
+
+	local := &pb.ReferencePointer{InternalRepositoryURL: "file:///repo"}
+	require.Equal(defaultImportPath, repoImportPath(context.Background(), logger, client, local))
+}
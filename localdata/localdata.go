@@ -0,0 +1,219 @@
+// Package localdata provides an in-memory implementation of pb.DataClient.
+// It's used to run the analyzer without a DataService.
+package localdata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// DataClient implements pb.DataClient serving in-memory changes and files.
+// Requests are filtered the same way lookout DataService does.
+type DataClient struct {
+	Changes []*pb.Change
+	Files   []*pb.File
+}
+
+var _ pb.DataClient = &DataClient{}
+
+// NewDataClient returns a DataClient serving the given changes
+func NewDataClient(changes ...*pb.Change) *DataClient {
+	return &DataClient{Changes: changes}
+}
+
+// GetChanges returns a stream of changes matching the request
+func (c *DataClient) GetChanges(ctx context.Context, in *pb.ChangesRequest,
+	opts ...grpc.CallOption) (pb.Data_GetChangesClient, error) {
+
+	filter, err := newFileFilter(in.IncludePattern, in.ExcludePattern,
+		in.ExcludeVendored, in.IncludeLanguages)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*pb.Change
+	for _, change := range c.Changes {
+		f := change.Head
+		if f == nil {
+			f = change.Base
+		}
+
+		if f == nil || !filter.match(f) {
+			continue
+		}
+
+		changes = append(changes, &pb.Change{
+			Base: filterContent(change.Base, in.WantContents),
+			Head: filterContent(change.Head, in.WantContents),
+		})
+	}
+
+	return NewChangesClient(ctx, changes), nil
+}
+
+// GetFiles returns a stream of files matching the request
+func (c *DataClient) GetFiles(ctx context.Context, in *pb.FilesRequest,
+	opts ...grpc.CallOption) (pb.Data_GetFilesClient, error) {
+
+	filter, err := newFileFilter(in.IncludePattern, in.ExcludePattern,
+		in.ExcludeVendored, in.IncludeLanguages)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*pb.File
+	for _, f := range c.Files {
+		if filter.match(f) {
+			files = append(files, filterContent(f, in.WantContents))
+		}
+	}
+
+	return NewFilesClient(ctx, files), nil
+}
+
+// stream implements grpc.ClientStream methods that make sense in-memory
+type stream struct {
+	grpc.ClientStream
+	ctx context.Context
+}
+
+func (s *stream) Header() (metadata.MD, error) { return nil, nil }
+func (s *stream) Trailer() metadata.MD         { return nil }
+func (s *stream) CloseSend() error             { return nil }
+func (s *stream) Context() context.Context     { return s.ctx }
+
+// ChangesClient implements pb.Data_GetChangesClient over a slice of changes
+type ChangesClient struct {
+	stream
+	changes []*pb.Change
+}
+
+// NewChangesClient returns a stream of the given changes
+func NewChangesClient(ctx context.Context, changes []*pb.Change) *ChangesClient {
+	return &ChangesClient{stream: stream{ctx: ctx}, changes: changes}
+}
+
+// Recv returns the next change or io.EOF when there are no more changes
+func (s *ChangesClient) Recv() (*pb.Change, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(s.changes) == 0 {
+		return nil, io.EOF
+	}
+
+	change := s.changes[0]
+	s.changes = s.changes[1:]
+	return change, nil
+}
+
+// FilesClient implements pb.Data_GetFilesClient over a slice of files
+type FilesClient struct {
+	stream
+	files []*pb.File
+}
+
+// NewFilesClient returns a stream of the given files
+func NewFilesClient(ctx context.Context, files []*pb.File) *FilesClient {
+	return &FilesClient{stream: stream{ctx: ctx}, files: files}
+}
+
+// Recv returns the next file or io.EOF when there are no more files
+func (s *FilesClient) Recv() (*pb.File, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(s.files) == 0 {
+		return nil, io.EOF
+	}
+
+	file := s.files[0]
+	s.files = s.files[1:]
+	return file, nil
+}
+
+// LanguageByPath detects language of a file by its extension.
+// Only languages the analyzer is interested in are known.
+func LanguageByPath(filePath string) string {
+	switch path.Ext(filePath) {
+	case ".go":
+		return "Go"
+	default:
+		return ""
+	}
+}
+
+var vendoredRegexp = regexp.MustCompile(`(^|/)(vendor|Godeps)/`)
+
+type fileFilter struct {
+	include, exclude *regexp.Regexp
+	excludeVendored  bool
+	languages        []string
+}
+
+func newFileFilter(include, exclude string, excludeVendored bool,
+	languages []string) (*fileFilter, error) {
+
+	f := &fileFilter{excludeVendored: excludeVendored, languages: languages}
+
+	var err error
+	if include != "" {
+		if f.include, err = regexp.Compile(include); err != nil {
+			return nil, fmt.Errorf("wrong include pattern: %s", err)
+		}
+	}
+
+	if exclude != "" {
+		if f.exclude, err = regexp.Compile(exclude); err != nil {
+			return nil, fmt.Errorf("wrong exclude pattern: %s", err)
+		}
+	}
+
+	return f, nil
+}
+
+func (f *fileFilter) match(file *pb.File) bool {
+	if f.include != nil && !f.include.MatchString(file.Path) {
+		return false
+	}
+
+	if f.exclude != nil && f.exclude.MatchString(file.Path) {
+		return false
+	}
+
+	if f.excludeVendored && vendoredRegexp.MatchString(file.Path) {
+		return false
+	}
+
+	if len(f.languages) == 0 {
+		return true
+	}
+
+	for _, lang := range f.languages {
+		if strings.EqualFold(lang, file.Language) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func filterContent(file *pb.File, wantContents bool) *pb.File {
+	if file == nil || wantContents {
+		return file
+	}
+
+	f := *file
+	f.Content = nil
+	return &f
+}
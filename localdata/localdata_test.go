@@ -0,0 +1,103 @@
+package localdata
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func changedPaths(t *testing.T, stream pb.Data_GetChangesClient) []string {
+	var paths []string
+	for {
+		change, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+		f := change.Head
+		if f == nil {
+			f = change.Base
+		}
+
+		paths = append(paths, f.Path)
+	}
+
+	return paths
+}
+
+func TestGetChanges(t *testing.T) {
+	require := require.New(t)
+
+	client := NewDataClient(
+		&pb.Change{Head: file("main.go", "package main\n")},
+		&pb.Change{Base: file("deleted.go", "package main\n")},
+		&pb.Change{Head: file("README.md", "# readme\n")},
+		&pb.Change{Head: file("vendor/dep/dep.go", "package dep\n")},
+		&pb.Change{Head: file("pkg/added.go", "package pkg\n")},
+	)
+
+	ctx := context.Background()
+	stream, err := client.GetChanges(ctx, &pb.ChangesRequest{
+		ExcludeVendored:  true,
+		IncludeLanguages: []string{"go"},
+	})
+	require.NoError(err)
+	require.Equal([]string{"main.go", "deleted.go", "pkg/added.go"}, changedPaths(t, stream))
+
+	stream, err = client.GetChanges(ctx, &pb.ChangesRequest{
+		IncludePattern: `\.go$`,
+		ExcludePattern: `^pkg/`,
+	})
+	require.NoError(err)
+	require.Equal([]string{"main.go", "deleted.go", "vendor/dep/dep.go"}, changedPaths(t, stream))
+
+	stream, err = client.GetChanges(ctx, &pb.ChangesRequest{WantContents: false})
+	require.NoError(err)
+	change, err := stream.Recv()
+	require.NoError(err)
+	require.Nil(change.Head.Content)
+	require.NotNil(client.Changes[0].Head.Content, "stored change must not be modified")
+
+	_, err = client.GetChanges(ctx, &pb.ChangesRequest{IncludePattern: "("})
+	require.Error(err)
+}
+
+func TestGetFiles(t *testing.T) {
+	require := require.New(t)
+
+	client := &DataClient{Files: []*pb.File{
+		file("main.go", "package main\n"),
+		file("README.md", "# readme\n"),
+	}}
+
+	stream, err := client.GetFiles(context.Background(), &pb.FilesRequest{
+		IncludePattern: `\.md$`,
+		WantContents:   true,
+	})
+	require.NoError(err)
+
+	f, err := stream.Recv()
+	require.NoError(err)
+	require.Equal("README.md", f.Path)
+	require.Equal("# readme\n", string(f.Content))
+
+	_, err = stream.Recv()
+	require.Equal(io.EOF, err)
+}
+
+func TestStreamContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := NewChangesClient(ctx, []*pb.Change{{Head: file("a.go", "")}})
+	cancel()
+
+	_, err := stream.Recv()
+	require.Equal(t, context.Canceled, err)
+}
+
+func file(filePath string, content string) *pb.File {
+	return &pb.File{Path: filePath, Content: []byte(content), Language: LanguageByPath(filePath)}
+}
@@ -0,0 +1,131 @@
+package gometalint
+
+import (
+	"regexp"
+	"strings"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// newIssuesMode configures reporting of issues that are new in the head revision
+type newIssuesMode struct {
+	// enabled lints the base revision as well and reports only new issues
+	enabled bool
+	// praiseFixed mentions issues fixed in the head revision
+	praiseFixed bool
+}
+
+// newIssuesConfig reads "newIssuesOnly" and "praiseFixed" options of the configuration
+func newIssuesConfig(logger log.Logger, s types.Struct) newIssuesMode {
+	var m newIssuesMode
+
+	config := s.GetFields()
+	options := []struct {
+		name  string
+		value *bool
+	}{
+		{"newIssuesOnly", &m.enabled},
+		{"praiseFixed", &m.praiseFixed},
+	}
+
+	for _, o := range options {
+		v, ok := config[o.name]
+		if !ok || v == nil {
+			continue
+		}
+
+		b, ok := v.GetKind().(*types.Value_BoolValue)
+		if !ok {
+			logger.Warningf("wrong type for %s argument", o.name)
+			continue
+		}
+
+		*o.value = b.BoolValue
+	}
+
+	return m
+}
+
+var (
+	numbers = regexp.MustCompile(`\d+`)
+	spaces  = regexp.MustCompile(`\s+`)
+)
+
+// normalizeMessage returns message of the comment with numbers and spaces
+// normalized, so issues can be matched after lines are moved or edited
+func normalizeMessage(c Comment) string {
+	msg := numbers.ReplaceAllString(c.Message(), "N")
+	return strings.TrimSpace(spaces.ReplaceAllString(msg, " "))
+}
+
+// issueKey identifies issues that are the same in different revisions
+type issueKey struct {
+	file    string
+	linter  string
+	message string
+}
+
+func keyOf(c Comment) issueKey {
+	return issueKey{file: c.file, linter: c.Linter(), message: normalizeMessage(c)}
+}
+
+// matchIssues splits issues of the head revision into new and pre-existing
+// ones and returns issues of the base revision that are fixed in the head.
+// Base issues must have paths of the head revision. Issues are matched on
+// lines mapped by the diff first, issues on new lines are then matched in
+// order to issues on base lines replaced by the same hunk, as edited lines
+// may keep the same issues.
+func matchIssues(head, base []Comment, bases *baseIndex) (newIssues, oldIssues, fixed []Comment) {
+	byKey := make(map[issueKey][]int)
+	for i, c := range base {
+		key := keyOf(c)
+		byKey[key] = append(byKey[key], i)
+	}
+
+	used := make([]bool, len(base))
+	matched := make([]bool, len(head))
+	// match matches the head issue to an unused base issue
+	// on lines from..to inclusive
+	match := func(h int, from, to int32) {
+		for _, i := range byKey[keyOf(head[h])] {
+			if !used[i] && base[i].lino >= from && base[i].lino <= to {
+				used[i] = true
+				matched[h] = true
+				return
+			}
+		}
+	}
+
+	for h, c := range head {
+		if line, ok := bases.baseLine(c.file, c.lino); ok {
+			match(h, line, line)
+		}
+	}
+
+	for h, c := range head {
+		if matched[h] {
+			continue
+		}
+
+		if from, to, ok := bases.baseHunk(c.file, c.lino); ok {
+			match(h, from+1, to-1)
+		}
+	}
+
+	for h, c := range head {
+		if matched[h] {
+			oldIssues = append(oldIssues, c)
+		} else {
+			newIssues = append(newIssues, c)
+		}
+	}
+
+	for i, c := range base {
+		if !used[i] {
+			fixed = append(fixed, c)
+		}
+	}
+
+	return newIssues, oldIssues, fixed
+}
@@ -0,0 +1,101 @@
+package gometalint
+
+import (
+	"testing"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestNewIssuesConfig(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(newIssuesMode{}, newIssuesConfig(logger, types.Struct{}))
+	require.Equal(newIssuesMode{enabled: true, praiseFixed: true},
+		newIssuesConfig(logger, *pb.ToStruct(map[string]interface{}{
+			"newIssuesOnly": true,
+			"praiseFixed":   true,
+		})))
+	require.Equal(newIssuesMode{praiseFixed: true},
+		newIssuesConfig(logger, *pb.ToStruct(map[string]interface{}{
+			"newIssuesOnly": "yes",
+			"praiseFixed":   true,
+		})))
+}
+
+func TestNormalizeMessage(t *testing.T) {
+	require.Equal(t, "line is N characters",
+		normalizeMessage(NewComment("warning", "a.go", 1, 0, " line is 130  characters (lll)")))
+	require.Equal(t, "cyclomatic complexity N of func `f` is high (> N)",
+		normalizeMessage(NewComment("warning", "a.go", 1, 0,
+			" cyclomatic complexity 12 of func `f` is high (> 10) (gocyclo)")))
+}
+
+func TestMatchIssues(t *testing.T) {
+	require := require.New(t)
+
+	bases := newBaseIndex()
+	bases.add(&pb.Change{
+		Base: &pb.File{Path: "a.go", Content: []byte("package a\nvar a = 1\nvar b = 1\nvar c = 1\n")},
+		Head: &pb.File{Path: "a.go", Content: []byte("package a\n\nvar a = 1\nvar b = 2\nvar c = 1\n")},
+	})
+
+	base := []Comment{
+		NewComment("warning", "a.go", 2, 0, " line is 120 characters (lll)"),
+		NewComment("warning", "a.go", 3, 0, " line is 121 characters (lll)"),
+		NewComment("warning", "a.go", 4, 0, " unsafe (gosec)"),
+		NewComment("warning", "a.go", 4, 0, " misspelled (misspell)"),
+	}
+
+	head := []Comment{
+		// moved
+		NewComment("warning", "a.go", 3, 0, " line is 120 characters (lll)"),
+		// edited
+		NewComment("warning", "a.go", 4, 0, " line is 122 characters (lll)"),
+		// the same line, another linter
+		NewComment("warning", "a.go", 5, 0, " unsafe call (gosec)"),
+		NewComment("warning", "a.go", 5, 0, " misspelled (misspell)"),
+		// new file
+		NewComment("warning", "b.go", 1, 0, " line is 120 characters (lll)"),
+	}
+
+	newIssues, oldIssues, fixed := matchIssues(head, base, bases)
+	require.Equal([]Comment{head[2], head[4]}, newIssues)
+	require.Equal([]Comment{head[0], head[1], head[3]}, oldIssues)
+	require.Equal([]Comment{base[2]}, fixed)
+}
+
+func TestMatchIssuesSameMessage(t *testing.T) {
+	require := require.New(t)
+
+	bases := newBaseIndex()
+	bases.add(&pb.Change{
+		Base: &pb.File{Path: "a.go", Content: []byte("package a\nfunc a() {\n\tf()\n\tf()\n}\n")},
+		Head: &pb.File{Path: "a.go", Content: []byte("package a\nfunc a() {\n\tf()\n}\n\nfunc b() {\n\tg()\n}\n")},
+	})
+	bases.add(&pb.Change{
+		Base: &pb.File{Path: "b.go", Content: []byte("package b\nfunc b() {\n\tf(1)\n}\n")},
+		Head: &pb.File{Path: "b.go", Content: []byte("package b\nfunc b() {\n\tf(2)\n\tg()\n}\n")},
+	})
+
+	base := []Comment{
+		NewComment("warning", "a.go", 3, 0, " error return value is not checked (errcheck)"),
+		NewComment("warning", "a.go", 4, 0, " error return value is not checked (errcheck)"),
+		NewComment("warning", "b.go", 3, 0, " error return value is not checked (errcheck)"),
+	}
+
+	head := []Comment{
+		NewComment("warning", "a.go", 3, 0, " error return value is not checked (errcheck)"),
+		// the second issue is fixed and the same one is introduced in another place
+		NewComment("warning", "a.go", 7, 0, " error return value is not checked (errcheck)"),
+		// the edited line and a new line of the same hunk
+		NewComment("warning", "b.go", 3, 0, " error return value is not checked (errcheck)"),
+		NewComment("warning", "b.go", 4, 0, " error return value is not checked (errcheck)"),
+	}
+
+	newIssues, oldIssues, fixed := matchIssues(head, base, bases)
+	require.Equal([]Comment{head[1], head[3]}, newIssues)
+	require.Equal([]Comment{head[0], head[2]}, oldIssues)
+	require.Equal([]Comment{base[1]}, fixed)
+}
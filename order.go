@@ -0,0 +1,114 @@
+package gometalint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+)
+
+// supersededBy maps linters to linters reporting the same issues and more,
+// e.g. goimports formats code like gofmt does
+var supersededBy = map[string]string{
+	"gofmt": "goimports",
+}
+
+// Fingerprints returns hashes identifying the issues across revisions.
+// They don't depend on lines and numbers in messages, so they stay the same
+// when the code is moved. Issues with the same message in the same file are
+// told apart by their order in the file.
+func Fingerprints(comments []Comment) []string {
+	order := make([]int, len(comments))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := comments[order[i]], comments[order[j]]
+		if a.lino != b.lino {
+			return a.lino < b.lino
+		}
+
+		return a.col < b.col
+	})
+
+	occurrences := make(map[issueKey]int)
+	fingerprints := make([]string, len(comments))
+	for _, i := range order {
+		key := keyOf(comments[i])
+		fingerprints[i] = fingerprint(key, occurrences[key])
+		occurrences[key]++
+	}
+
+	return fingerprints
+}
+
+// fingerprint returns hash of the issue and its number among the same issues
+func fingerprint(key issueKey, occurrence int) string {
+	h := sha256.New()
+	for _, s := range []string{key.file, key.linter, key.message, strconv.Itoa(occurrence)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// SortComments sorts comments by file, line and linter, and removes
+// duplicates and issues reported by superseding linters on the same line
+func SortComments(comments []Comment) []Comment {
+	sorted := append([]Comment(nil), comments...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+
+		if a.lino != b.lino {
+			return a.lino < b.lino
+		}
+
+		if a.Linter() != b.Linter() {
+			return a.Linter() < b.Linter()
+		}
+
+		if a.col != b.col {
+			return a.col < b.col
+		}
+
+		if a.text != b.text {
+			return a.text < b.text
+		}
+
+		return a.level < b.level
+	})
+
+	type fileLine struct {
+		file string
+		line int32
+	}
+
+	linters := make(map[fileLine]map[string]bool)
+	for _, c := range sorted {
+		key := fileLine{c.file, c.lino}
+		if linters[key] == nil {
+			linters[key] = make(map[string]bool)
+		}
+		linters[key][c.Linter()] = true
+	}
+
+	var result []Comment
+	for i, c := range sorted {
+		if i > 0 && c == sorted[i-1] {
+			continue
+		}
+
+		if by, ok := supersededBy[c.Linter()]; ok && linters[fileLine{c.file, c.lino}][by] {
+			continue
+		}
+
+		result = append(result, c)
+	}
+
+	return result
+}
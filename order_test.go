@@ -0,0 +1,62 @@
+package gometalint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortComments(t *testing.T) {
+	comments := []Comment{
+		NewComment("warning", "b.go", 1, 0, " line is 130 characters (lll)"),
+		NewComment("warning", "a.go", 3, 0, " line is 130 characters (lll)"),
+		NewComment("warning", "a.go", 3, 0, ` "langauge" is a misspelling (misspell)`),
+		NewComment("warning", "a.go", 0, 0, " file is not gofmted with -s (gofmt)"),
+		NewComment("warning", "a.go", 3, 0, " line is 130 characters (lll)"),
+		NewComment("warning", "a.go", 0, 0, " file is not goimported (goimports)"),
+		NewComment("warning", "b.go", 0, 0, " file is not gofmted with -s (gofmt)"),
+		NewComment("error", "a.go", 1, 2, " G104: Errors unhandled. (gosec)"),
+		NewComment("error", "a.go", 1, 1, " G104: Errors unhandled. (gosec)"),
+	}
+
+	require.Equal(t, []Comment{
+		comments[5],
+		comments[8],
+		comments[7],
+		comments[1],
+		comments[2],
+		comments[6],
+		comments[0],
+	}, SortComments(comments))
+
+	require.Empty(t, SortComments(nil))
+}
+
+func TestFingerprints(t *testing.T) {
+	require := require.New(t)
+
+	fp := func(comments ...Comment) []string { return Fingerprints(comments) }
+
+	c := NewComment("warning", "a.go", 3, 5, " line is 130 characters (lll)")
+	require.Len(fp(c)[0], 32)
+	require.Equal(fp(c),
+		fp(NewComment("error", "a.go", 10, 1, "line is 131 characters (lll)")),
+		"fingerprint must not depend on position and numbers")
+	require.NotEqual(fp(c),
+		fp(NewComment("warning", "b.go", 3, 5, " line is 130 characters (lll)")))
+	require.NotEqual(fp(c),
+		fp(NewComment("warning", "a.go", 3, 5, " line is 130 characters (long)")))
+
+	second := NewComment("warning", "a.go", 8, 1, " line is 125 characters (lll)")
+	other := NewComment("warning", "b.go", 1, 1, " line is 130 characters (lll)")
+	fps := fp(second, other, c)
+	require.NotEqual(fps[0], fps[2], "the same issues in a file must have different fingerprints")
+	require.Equal(fp(c)[0], fps[2], "the first issue in the file must keep its fingerprint")
+	require.Equal(fp(other)[0], fps[1])
+	require.Equal(fps, fp(second, other, c), "fingerprints must be stable")
+
+	moved := NewComment("warning", "a.go", 20, 1, " line is 125 characters (lll)")
+	require.Equal(fps[0], fp(c, moved)[1], "fingerprint must not change when the code is moved")
+
+	require.Empty(fp())
+}
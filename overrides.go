@@ -0,0 +1,278 @@
+package gometalint
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// enabledOption is the option of every linter to enable or disable it
+const enabledOption = "enabled"
+
+// linterSettings are options of linters by linter name
+type linterSettings map[string]map[string]*types.Value
+
+// readLinterSettings reads "linters" list of the configuration
+// and returns settings with the options merged over the base ones
+func readLinterSettings(logger log.Logger, v *types.Value, base linterSettings) linterSettings {
+	settings := base.merge(nil)
+
+	list := v.GetListValue()
+	if list == nil {
+		return settings
+	}
+
+	for _, v := range list.GetValues() {
+		fields := v.GetStructValue().GetFields()
+		nameV, ok := fields["name"]
+		if !ok || nameV == nil {
+			continue
+		}
+
+		name := nameV.GetStringValue()
+		if !isKnownLinter(name) {
+			logger.Warningf("unknown linter %s", name)
+			continue
+		}
+
+		opts := make(map[string]*types.Value)
+		for option, optV := range fields {
+			_, isOption := linterOptions(name)[option]
+			if optV != nil && (option == enabledOption || isOption) {
+				opts[option] = optV
+			}
+		}
+
+		settings = settings.merge(linterSettings{name: opts})
+	}
+
+	return settings
+}
+
+// merge returns new settings with options of other overriding the ones of s
+func (s linterSettings) merge(other linterSettings) linterSettings {
+	merged := make(linterSettings)
+	for _, settings := range []linterSettings{s, other} {
+		for name, opts := range settings {
+			if merged[name] == nil {
+				merged[name] = make(map[string]*types.Value)
+			}
+
+			for option, v := range opts {
+				merged[name][option] = v
+			}
+		}
+	}
+
+	return merged
+}
+
+// arguments converts the settings to gometalint arguments, in stable order.
+// Custom linters run by gometalinter are defined and enabled by the arguments.
+func (s linterSettings) arguments(logger log.Logger) []string {
+	var names []string
+	for name := range s {
+		if isDefaultLinter(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var args []string
+	for _, name := range names {
+		opts := s[name]
+		if !s.enabled(logger, name) {
+			args = append(args, "--disable="+name)
+			continue
+		}
+
+		var options []string
+		for option := range lintersOptions[name] {
+			options = append(options, option)
+		}
+		sort.Strings(options)
+
+		for _, option := range options {
+			optV, ok := opts[option]
+			if !ok {
+				continue
+			}
+
+			if arg := lintersOptions[name][option].argument(logger, optV); arg != "" {
+				args = append(args, arg)
+			}
+		}
+	}
+
+	for _, name := range customLinterNames() {
+		l, ok := customLinter(name)
+		if ok && l.Gometalinter && s.enabled(logger, name) {
+			args = append(args, l.gometalinterArguments(l.arguments(logger, s[name]))...)
+		}
+	}
+
+	return args
+}
+
+// commands returns runs of enabled custom linters that are not run by gometalinter
+func (s linterSettings) commands(logger log.Logger) []customCommand {
+	var commands []customCommand
+	for _, name := range customLinterNames() {
+		l, ok := customLinter(name)
+		if ok && !l.Gometalinter && s.enabled(logger, name) {
+			commands = append(commands, customCommand{linter: l, args: l.arguments(logger, s[name])})
+		}
+	}
+
+	return commands
+}
+
+// enabled returns false if the linter is disabled by the settings
+func (s linterSettings) enabled(logger log.Logger, name string) bool {
+	v, ok := s[name][enabledOption]
+	if !ok {
+		return true
+	}
+
+	b, ok := v.GetKind().(*types.Value_BoolValue)
+	if !ok {
+		logger.Warningf("wrong type for %s:%s argument", name, enabledOption)
+		return true
+	}
+
+	return b.BoolValue
+}
+
+// override changes linter settings for files matching any of globs
+type override struct {
+	globs   []string
+	linters linterSettings
+}
+
+// matches returns true if the slash-separated path or any of its parent
+// directories matches any glob of the override
+func (o override) matches(p string) bool {
+	for ; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if matchGlobs(p, o.globs) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// overridesConfig reads "overrides" list of the configuration, every entry
+// has "paths" list of globs and "linters" list with the same format as
+// the top-level one
+func overridesConfig(logger log.Logger, s types.Struct) []override {
+	v, ok := s.GetFields()["overrides"]
+	if !ok || v == nil {
+		return nil
+	}
+
+	list := v.GetListValue()
+	if list == nil {
+		logger.Warningf("wrong type for overrides argument")
+		return nil
+	}
+
+	var overrides []override
+	for i, v := range list.GetValues() {
+		fields := v.GetStructValue().GetFields()
+		if fields == nil {
+			logger.Warningf("wrong type for overrides[%d] argument", i)
+			continue
+		}
+
+		var o override
+		for _, glob := range fields["paths"].GetListValue().GetValues() {
+			g := glob.GetStringValue()
+			if _, err := path.Match(g, ""); g == "" || err != nil {
+				logger.Warningf("wrong glob %q in overrides[%d] argument", g, i)
+				continue
+			}
+
+			o.globs = append(o.globs, g)
+		}
+
+		if len(o.globs) == 0 {
+			logger.Warningf("no paths in overrides[%d] argument", i)
+			continue
+		}
+
+		o.linters = readLinterSettings(logger, fields["linters"], nil)
+		overrides = append(overrides, o)
+	}
+
+	return overrides
+}
+
+// lintGroup is a set of files linted with the same arguments
+type lintGroup struct {
+	args []string
+	// commands are runs of custom linters not run by gometalinter
+	commands []customCommand
+}
+
+// lintGroups assigns files to groups by linter arguments with the overrides
+// matching the files applied
+type lintGroups struct {
+	logger    log.Logger
+	base      linterSettings
+	overrides []override
+	// groups caches groups by indexes of matched overrides
+	groups map[string]*lintGroup
+	byArgs map[string]*lintGroup
+}
+
+// newLintGroups reads linter settings and overrides from the configuration,
+// the settings are merged over the base ones
+func newLintGroups(logger log.Logger, s types.Struct, base linterSettings) *lintGroups {
+	return &lintGroups{
+		logger:    logger,
+		base:      readLinterSettings(logger, s.GetFields()["linters"], base),
+		overrides: overridesConfig(logger, s),
+		groups:    make(map[string]*lintGroup),
+		byArgs:    make(map[string]*lintGroup),
+	}
+}
+
+// get returns the group of the file, the same for files with the same arguments
+func (g *lintGroups) get(p string) *lintGroup {
+	var matched []string
+	settings := g.base
+	for i, o := range g.overrides {
+		if o.matches(p) {
+			matched = append(matched, strconv.Itoa(i))
+			settings = settings.merge(o.linters)
+		}
+	}
+
+	key := strings.Join(matched, ",")
+	if group, ok := g.groups[key]; ok {
+		return group
+	}
+
+	group := &lintGroup{
+		args:     settings.arguments(g.logger),
+		commands: settings.commands(g.logger),
+	}
+
+	groupKey := strings.Join(group.args, "\x00")
+	for _, cmd := range group.commands {
+		groupKey += "\x00" + cmd.linter.Name + "\x00" + strings.Join(cmd.args, "\x00")
+	}
+
+	if existing, ok := g.byArgs[groupKey]; ok {
+		group = existing
+	} else {
+		g.byArgs[groupKey] = group
+	}
+
+	g.groups[key] = group
+	return group
+}
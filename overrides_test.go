@@ -0,0 +1,110 @@
+package gometalint
+
+import (
+	"testing"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+var overridesConf = map[string]interface{}{
+	"linters": []map[string]interface{}{
+		{"name": "lll", "maxLen": 120},
+		{"name": "gocyclo", "over": 10},
+	},
+	"overrides": []map[string]interface{}{
+		{
+			"paths": []string{"cmd/*"},
+			"linters": []map[string]interface{}{
+				{"name": "lll", "maxLen": 80},
+				{"name": "gocyclo", "enabled": false},
+			},
+		},
+		{
+			"paths": []string{"*_test.go"},
+			"linters": []map[string]interface{}{
+				{"name": "lll", "enabled": false},
+			},
+		},
+		{
+			"paths": []string{"pkg"},
+			"linters": []map[string]interface{}{
+				{"name": "gocyclo", "over": 15},
+			},
+		},
+	},
+}
+
+func TestOverrideMatches(t *testing.T) {
+	require := require.New(t)
+
+	o := override{globs: []string{"cmd/*", "*_test.go"}}
+	require.True(o.matches("cmd/tool/main.go"))
+	require.True(o.matches("cmd/main.go"))
+	require.True(o.matches("pkg/a_test.go"))
+	require.False(o.matches("cmd"))
+	require.False(o.matches("pkg/cmd/main.go"))
+	require.False(o.matches("pkg/a.go"))
+}
+
+func TestOverridesConfigWrong(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(overridesConfig(logger, types.Struct{}))
+	require.Nil(overridesConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"overrides": "cmd/*",
+	})))
+
+	overrides := overridesConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"overrides": []interface{}{
+			"cmd/*",
+			map[string]interface{}{"linters": []interface{}{}},
+			map[string]interface{}{"paths": []string{"[", ""}},
+			map[string]interface{}{"paths": []string{"[", "cmd/*"}},
+		},
+	}))
+	require.Len(overrides, 1)
+	require.Equal([]string{"cmd/*"}, overrides[0].globs)
+}
+
+func TestLintGroups(t *testing.T) {
+	require := require.New(t)
+
+	g := newLintGroups(logger, *pb.ToStruct(overridesConf), nil)
+
+	files := []struct {
+		path string
+		args []string
+	}{
+		{"a.go", []string{"--cyclo-over=10", "--line-length=120"}},
+		{"cmd/tool/main.go", []string{"--disable=gocyclo", "--line-length=80"}},
+		{"cmd/tool/main_test.go", []string{"--disable=gocyclo", "--disable=lll"}},
+		{"pkg/a.go", []string{"--cyclo-over=15", "--line-length=120"}},
+		{"pkg/a_test.go", []string{"--cyclo-over=15", "--disable=lll"}},
+	}
+
+	for _, f := range files {
+		require.Equal(f.args, g.get(f.path).args, f.path)
+	}
+
+	require.True(g.get("a.go") == g.get("b.go"), "files with the same args must be in the same group")
+}
+
+func TestLintGroupsSameArgs(t *testing.T) {
+	require := require.New(t)
+
+	g := newLintGroups(logger, *pb.ToStruct(map[string]interface{}{
+		"overrides": []map[string]interface{}{
+			{
+				"paths": []string{"cmd/*"},
+				"linters": []map[string]interface{}{
+					{"name": "lll", "maxLen": "not a number"},
+				},
+			},
+		},
+	}), nil)
+
+	require.Equal(g.get("a.go"), g.get("cmd/tool/main.go"))
+	require.Empty(g.get("a.go").args)
+}
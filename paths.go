@@ -0,0 +1,107 @@
+package gometalint
+
+import (
+	"regexp"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// pathFilter limits analyzed files and reported comments by their paths
+type pathFilter struct {
+	include, exclude *regexp.Regexp
+	// linters maps linter name to regexp of paths its comments are dropped for
+	linters map[string]*regexp.Regexp
+}
+
+// includePattern returns the pattern of files requested from DataService
+func (f pathFilter) includePattern() string {
+	if f.include == nil {
+		return ""
+	}
+
+	return f.include.String()
+}
+
+// excludePattern returns the pattern of files excluded in DataService requests
+func (f pathFilter) excludePattern() string {
+	if f.exclude == nil {
+		return ""
+	}
+
+	return f.exclude.String()
+}
+
+// matchFile returns true if the file must be analyzed
+func (f pathFilter) matchFile(path string) bool {
+	if f.include != nil && !f.include.MatchString(path) {
+		return false
+	}
+
+	return f.exclude == nil || !f.exclude.MatchString(path)
+}
+
+// keepComment returns true if the comment of the linter about the file
+// must be reported
+func (f pathFilter) keepComment(linter, path string) bool {
+	if path != "" && !f.matchFile(path) {
+		return false
+	}
+
+	re, ok := f.linters[linter]
+	return !ok || !re.MatchString(path)
+}
+
+// pathsConfig reads path options from the configuration: "include" and
+// "exclude" regexps for all files and "exclude" regexp of every linter
+func pathsConfig(logger log.Logger, s types.Struct) pathFilter {
+	f := pathFilter{linters: make(map[string]*regexp.Regexp)}
+
+	config := s.GetFields()
+	if config == nil {
+		return f
+	}
+
+	f.include = regexpOption(logger, "include", config["include"])
+	f.exclude = regexpOption(logger, "exclude", config["exclude"])
+
+	linters := config["linters"].GetListValue()
+	for _, v := range linters.GetValues() {
+		fields := v.GetStructValue().GetFields()
+		name := fields["name"].GetStringValue()
+		if !isKnownLinter(name) {
+			continue
+		}
+
+		if re := regexpOption(logger, name+":exclude", fields["exclude"]); re != nil {
+			f.linters[name] = re
+		}
+	}
+
+	return f
+}
+
+// regexpOption returns compiled regexp of the option, nil if it's not set or wrong
+func regexpOption(logger log.Logger, name string, v *types.Value) *regexp.Regexp {
+	if v == nil {
+		return nil
+	}
+
+	sv, ok := v.GetKind().(*types.Value_StringValue)
+	if !ok {
+		logger.Warningf("wrong type for %s argument", name)
+		return nil
+	}
+
+	if sv.StringValue == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(sv.StringValue)
+	if err != nil {
+		logger.Warningf("wrong regexp for %s argument: %s", name, err)
+		return nil
+	}
+
+	return re
+}
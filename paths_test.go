@@ -0,0 +1,64 @@
+package gometalint
+
+import (
+	"testing"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestPathsConfigEmpty(t *testing.T) {
+	require := require.New(t)
+
+	f := pathsConfig(logger, types.Struct{})
+	require.Equal("", f.includePattern())
+	require.Equal("", f.excludePattern())
+	require.True(f.matchFile("a.go"))
+	require.True(f.keepComment("lll", "a_test.go"))
+}
+
+func TestPathsConfig(t *testing.T) {
+	require := require.New(t)
+
+	f := pathsConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"include": `^(cmd|pkg)/`,
+		"exclude": `^pkg/legacy/`,
+		"linters": []map[string]interface{}{
+			{"name": "lll", "exclude": `_test\.go$`},
+			{"name": "gosec", "exclude": `(^|/)testdata/`},
+			{"name": "unknown", "exclude": `.*`},
+		},
+	}))
+	require.Equal(`^(cmd|pkg)/`, f.includePattern())
+	require.Equal(`^pkg/legacy/`, f.excludePattern())
+
+	require.True(f.matchFile("pkg/a.go"))
+	require.False(f.matchFile("a.go"))
+	require.False(f.matchFile("pkg/legacy/a.go"))
+
+	require.True(f.keepComment("lll", "pkg/a.go"))
+	require.False(f.keepComment("lll", "pkg/a_test.go"))
+	require.True(f.keepComment("gosec", "pkg/a_test.go"))
+	require.False(f.keepComment("gosec", "pkg/testdata/a.go"))
+	require.True(f.keepComment("dupl", "pkg/testdata/a.go"))
+	require.False(f.keepComment("dupl", "pkg/legacy/a.go"))
+	require.True(f.keepComment("lll", ""), "global comments must be kept")
+	require.NotContains(f.linters, "unknown")
+}
+
+func TestPathsConfigWrong(t *testing.T) {
+	require := require.New(t)
+
+	f := pathsConfig(logger, *pb.ToStruct(map[string]interface{}{
+		"include": 1,
+		"exclude": `(`,
+		"linters": []map[string]interface{}{
+			{"name": "lll", "exclude": true},
+			{"name": "gosec", "exclude": `[`},
+		},
+	}))
+	require.Nil(f.include)
+	require.Nil(f.exclude)
+	require.Empty(f.linters)
+}
@@ -0,0 +1,218 @@
+package gometalint
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// lintJob is a set of files of one directory linted with the same arguments
+type lintJob struct {
+	// dir is the slash-separated directory of the files in the repository
+	dir   string
+	group *lintGroup
+	// files are paths of the files in the repository
+	files  map[string]bool
+	issues []Comment
+	// unwritten are files reserved in the workspace but not written yet,
+	// as earlier jobs of the directory may be running
+	unwritten []*pb.File
+}
+
+// lintFunc lints files of the group in the directory and returns issues
+// with original paths, linters are killed if the context is done
+type lintFunc func(ctx context.Context, dir string, group *lintGroup) []Comment
+
+// pipeline saves files to the workspace and lints them in jobs by directory.
+// Files are expected to arrive ordered by path, so jobs of a directory are
+// started as soon as a file of another directory arrives. Files of a directory
+// whose jobs are already started are linted in one more job per group when
+// all files are received and the earlier jobs are finished, the files are
+// written to the workspace only then, so running linters never see them. Jobs run concurrently, limited by the shared semaphore.
+// Jobs are dropped or killed when the context is done or the pipeline is closed.
+type pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	logger log.Logger
+	ws     *workspace
+	groups *lintGroups
+	lint   lintFunc
+	sem    chan struct{}
+
+	wg sync.WaitGroup
+	// jobs in order of creation
+	jobs []*lintJob
+	// open are jobs of the current directory by group
+	open    map[*lintGroup]*lintJob
+	openDir string
+	// dispatched are directories with started jobs
+	dispatched map[string]bool
+	// late are jobs of files of dispatched directories by directory and group,
+	// they are started by Wait after all earlier jobs
+	late map[string]map[*lintGroup]*lintJob
+	// start is the time the first job started
+	start time.Time
+}
+
+func newPipeline(ctx context.Context, logger log.Logger, ws *workspace,
+	groups *lintGroups, lint lintFunc, sem chan struct{}) *pipeline {
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &pipeline{
+		ctx:        ctx,
+		cancel:     cancel,
+		logger:     logger,
+		ws:         ws,
+		groups:     groups,
+		lint:       lint,
+		sem:        sem,
+		open:       make(map[*lintGroup]*lintJob),
+		dispatched: make(map[string]bool),
+		late:       make(map[string]map[*lintGroup]*lintJob),
+	}
+}
+
+// Save saves the file to the workspace and adds it to the job of its
+// directory and group, jobs of the previous directory are started.
+// Files of directories with started jobs are only reserved in the workspace.
+func (p *pipeline) Save(file *pb.File) error {
+	dir := path.Dir(file.Path)
+	if dir != p.openDir {
+		p.dispatch(p.open)
+		p.open = make(map[*lintGroup]*lintJob)
+		p.openDir = dir
+	}
+
+	late := p.dispatched[dir]
+	save := p.ws.Save
+	if late {
+		save = p.ws.Reserve
+	}
+
+	if err := save(file); err != nil {
+		return err
+	}
+
+	jobs := p.open
+	if late {
+		if p.late[dir] == nil {
+			p.late[dir] = make(map[*lintGroup]*lintJob)
+		}
+
+		jobs = p.late[dir]
+	}
+
+	group := p.groups.get(file.Path)
+	job, ok := jobs[group]
+	if !ok {
+		job = &lintJob{dir: dir, group: group, files: make(map[string]bool)}
+		p.jobs = append(p.jobs, job)
+		jobs[group] = job
+	}
+
+	job.files[path.Clean(file.Path)] = true
+	if late {
+		job.unwritten = append(job.unwritten, file)
+	}
+
+	return nil
+}
+
+// dispatch starts the jobs in order of creation. Linters see all files
+// of the directory, only issues of the files of the job are kept.
+func (p *pipeline) dispatch(jobs map[*lintGroup]*lintJob) {
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	for i, job := range p.jobs {
+		if jobs[job.group] != job {
+			continue
+		}
+
+		if p.start.IsZero() {
+			p.start = time.Now()
+		}
+
+		p.dispatched[job.dir] = true
+		p.wg.Add(1)
+		go func(i int, job *lintJob) {
+			defer p.wg.Done()
+
+			select {
+			case p.sem <- struct{}{}:
+				defer func() { <-p.sem }()
+			case <-p.ctx.Done():
+				p.logger.Debugf("job %d in %s dropped: %s", i, job.dir, p.ctx.Err())
+				return
+			}
+
+			if p.ctx.Err() != nil {
+				return
+			}
+
+			p.logger.Debugf("linting %d files of job %d in %s", len(job.files), i, job.dir)
+			for _, issue := range p.lint(p.ctx, p.ws.path(job.dir), job.group) {
+				if job.files[issue.file] {
+					job.issues = append(job.issues, issue)
+				}
+			}
+		}(i, job)
+	}
+}
+
+// Wait starts the remaining jobs, waits for all of them and returns issues
+// in order of jobs, so the result doesn't depend on scheduling. Time from
+// the start of the first job is returned as well.
+func (p *pipeline) Wait() ([]Comment, time.Duration) {
+	p.dispatch(p.open)
+	p.open = make(map[*lintGroup]*lintJob)
+	p.wg.Wait()
+
+	for dir, jobs := range p.late {
+		for _, job := range jobs {
+			p.write(job)
+		}
+
+		p.dispatch(jobs)
+		delete(p.late, dir)
+	}
+
+	p.wg.Wait()
+
+	var issues []Comment
+	for _, job := range p.jobs {
+		issues = append(issues, job.issues...)
+	}
+
+	if p.start.IsZero() {
+		return issues, 0
+	}
+
+	return issues, time.Since(p.start)
+}
+
+// write writes the unwritten files of the job to the workspace,
+// files that fail are removed from the job
+func (p *pipeline) write(job *lintJob) {
+	for _, file := range job.unwritten {
+		if err := p.ws.Write(file); err != nil {
+			p.logger.Warningf("skipping file %q: %s", file.Path, err)
+			delete(job.files, path.Clean(file.Path))
+		}
+	}
+
+	job.unwritten = nil
+}
+
+// Close aborts the pipeline: jobs that aren't started are dropped and linters
+// of the running ones are killed. It returns when all started jobs return,
+// so the workspace can be removed after it.
+func (p *pipeline) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
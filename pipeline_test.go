@@ -0,0 +1,252 @@
+package gometalint
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestPipeline(t *testing.T) {
+	require := require.New(t)
+
+	ws, err := newWorkspace(Limits{}, defaultImportPath)
+	require.NoError(err)
+	defer ws.Close()
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var dirs []string
+	lint := func(ctx context.Context, dir string, group *lintGroup) []Comment {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		infos, err := ioutil.ReadDir(dir)
+		require.NoError(err)
+
+		var issues []Comment
+		for _, info := range infos {
+			if !info.IsDir() {
+				name := revertOriginalPath(filepath.Join(dir, info.Name()), ws.root)
+				issues = append(issues, NewComment("warning", name, 1, 0, " issue (fake)"))
+			}
+		}
+
+		// jobs of deeper directories finish first
+		time.Sleep(time.Duration(10-strings.Count(dir, string(filepath.Separator))) * time.Millisecond)
+
+		mu.Lock()
+		running--
+		dirs = append(dirs, revertOriginalPath(dir, ws.root))
+		mu.Unlock()
+		return issues
+	}
+
+	groups := newLintGroups(logger, *pb.ToStruct(map[string]interface{}{
+		"overrides": []map[string]interface{}{
+			{"paths": []string{"*_test.go"}, "linters": []map[string]interface{}{
+				{"name": "lll", "enabled": false},
+			}},
+		},
+	}), nil)
+	p := newPipeline(context.Background(), logger, ws, groups, lint, make(chan struct{}, 2))
+
+	paths := []string{"a.go", "b.go", "pkg/a.go", "pkg/a_test.go", "pkg/b.go", "cmd/main.go", "c.go"}
+	for _, path := range paths {
+		require.NoError(p.Save(&pb.File{Path: path}))
+	}
+	require.Error(p.Save(&pb.File{Path: "../d.go"}))
+
+	issues, d := p.Wait()
+	require.True(d > 0)
+	require.True(maxRunning <= 2, "jobs must be limited by the semaphore")
+
+	var files []string
+	for _, issue := range issues {
+		files = append(files, issue.file)
+	}
+	require.Equal([]string{"a.go", "b.go", "pkg/a.go", "pkg/b.go", "pkg/a_test.go",
+		"cmd/main.go", "c.go"}, files, "issues must be in order of jobs, only of files of the job")
+
+	sort.Strings(dirs)
+	require.Equal([]string{"", "", "cmd", "pkg", "pkg"}, dirs)
+}
+
+func TestPipelineStreaming(t *testing.T) {
+	require := require.New(t)
+
+	ws, err := newWorkspace(Limits{}, defaultImportPath)
+	require.NoError(err)
+	defer ws.Close()
+
+	started := make(chan string, 10)
+	lint := func(ctx context.Context, dir string, group *lintGroup) []Comment {
+		started <- revertOriginalPath(dir, ws.root)
+		return nil
+	}
+
+	p := newPipeline(context.Background(), logger, ws, newLintGroups(logger, types.Struct{}, nil), lint, make(chan struct{}, 2))
+	require.NoError(p.Save(&pb.File{Path: "a.go"}))
+	require.NoError(p.Save(&pb.File{Path: "pkg/a.go"}))
+
+	select {
+	case dir := <-started:
+		require.Equal("", dir, "job of the previous directory must start")
+	case <-time.After(5 * time.Second):
+		require.Fail("job must start before all files are received")
+	}
+
+	// files of the dispatched directory arriving out of order
+	// are linted in one more job
+	for _, path := range []string{"b.go", "pkg/b.go", "c.go", "pkg/c.go", "d.go"} {
+		require.NoError(p.Save(&pb.File{Path: path}))
+	}
+
+	p.Wait()
+	close(started)
+
+	var dirs []string
+	for dir := range started {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	require.Equal([]string{"", "pkg", "pkg"}, dirs)
+}
+
+func TestPipelineLateFiles(t *testing.T) {
+	require := require.New(t)
+
+	ws, err := newWorkspace(Limits{}, defaultImportPath)
+	require.NoError(err)
+	defer ws.Close()
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var seen [][]string
+	lint := func(ctx context.Context, dir string, group *lintGroup) []Comment {
+		if revertOriginalPath(dir, ws.root) == "" {
+			select {
+			case started <- struct{}{}:
+				<-release
+			default:
+			}
+		}
+
+		infos, err := ioutil.ReadDir(dir)
+		require.NoError(err)
+
+		var names []string
+		for _, info := range infos {
+			if !info.IsDir() {
+				names = append(names, info.Name())
+			}
+		}
+
+		mu.Lock()
+		seen = append(seen, names)
+		mu.Unlock()
+		return nil
+	}
+
+	p := newPipeline(context.Background(), logger, ws, newLintGroups(logger, types.Struct{}, nil),
+		lint, make(chan struct{}, 2))
+	require.NoError(p.Save(&pb.File{Path: "a.go"}))
+	require.NoError(p.Save(&pb.File{Path: "pkg/a.go"}))
+	<-started
+
+	require.NoError(p.Save(&pb.File{Path: "b.go"}))
+	require.Error(p.Save(&pb.File{Path: "B.go"}), "late files must be checked when received")
+	close(release)
+	p.Wait()
+
+	require.Equal([][]string{{"a.go"}, {"a.go"}, {"a.go", "b.go"}}, seen,
+		"late files must be written only after earlier jobs of the directory finish")
+}
+
+func TestPipelineClose(t *testing.T) {
+	require := require.New(t)
+
+	ws, err := newWorkspace(Limits{}, defaultImportPath)
+	require.NoError(err)
+	defer ws.Close()
+
+	started := make(chan string, 10)
+	lint := func(ctx context.Context, dir string, group *lintGroup) []Comment {
+		started <- revertOriginalPath(dir, ws.root)
+		<-ctx.Done()
+		return nil
+	}
+
+	p := newPipeline(context.Background(), logger, ws, newLintGroups(logger, types.Struct{}, nil),
+		lint, make(chan struct{}, 1))
+	for _, path := range []string{"a.go", "pkg/a.go", "cmd/main.go", "b.go"} {
+		require.NoError(p.Save(&pb.File{Path: path}))
+	}
+
+	// one of the dispatched jobs runs, the other one waits for the semaphore
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		require.Fail("running jobs must be cancelled")
+	}
+
+	close(started)
+	var dirs []string
+	for dir := range started {
+		dirs = append(dirs, dir)
+	}
+	require.Empty(dirs, "jobs waiting for the semaphore, open and late jobs must be dropped")
+}
+
+func TestPipelineCancel(t *testing.T) {
+	require := require.New(t)
+
+	ws, err := newWorkspace(Limits{}, defaultImportPath)
+	require.NoError(err)
+	defer ws.Close()
+
+	lint := func(ctx context.Context, dir string, group *lintGroup) []Comment {
+		require.Fail("jobs must not run after the context is done")
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := newPipeline(ctx, logger, ws, newLintGroups(logger, types.Struct{}, nil), lint, make(chan struct{}, 1))
+	require.NoError(p.Save(&pb.File{Path: "a.go"}))
+	require.NoError(p.Save(&pb.File{Path: "pkg/a.go"}))
+	issues, _ := p.Wait()
+	require.Empty(issues)
+}
+
+func TestPipelineEmpty(t *testing.T) {
+	ws, err := newWorkspace(Limits{}, defaultImportPath)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	p := newPipeline(context.Background(), logger, ws, newLintGroups(logger, types.Struct{}, nil), nil, make(chan struct{}, 1))
+	issues, d := p.Wait()
+	require.Empty(t, issues)
+	require.Equal(t, time.Duration(0), d)
+}
@@ -0,0 +1,241 @@
+package gometalint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// Profile is the server-wide configuration of linters and reviews
+type Profile struct {
+	// Linters are the default settings of linters in the format of "linters"
+	// list of the analyzer configuration, repository settings are merged over them
+	Linters *types.Value
+	// Deadline of a gometalinter run, the gometalinter default if it's 0
+	Deadline time.Duration
+	// Limits of files analyzed in one review
+	Limits Limits
+	// Concurrency is the number of lint jobs running at the same time,
+	// the number of CPUs if it's not positive
+	Concurrency int
+	// Repositories are default settings of repositories,
+	// all the matching ones are applied in order
+	Repositories []RepositoryProfile
+}
+
+// RepositoryProfile is default analyzer settings of repositories
+type RepositoryProfile struct {
+	// Repos are globs of full names of repositories, e.g. "src-d/*"
+	Repos []string
+	// Settings in the format of the analyzer configuration,
+	// the repository configuration is merged over them
+	Settings types.Struct
+}
+
+// matches returns true if the full name of the repository matches any glob
+func (r RepositoryProfile) matches(fullName string) bool {
+	return matchGlobs(fullName, r.Repos)
+}
+
+// profileSchema is the schema of the profile file
+var profileSchema = objectSchema{fields: map[string]valueSchema{
+	"linters": lintersSchema(map[string]valueSchema{
+		enabledOption: boolSchema{},
+	}),
+	"deadline":     durationSchema,
+	"maxFileSize":  intSchema{min: 0},
+	"maxTotalSize": intSchema{min: 0},
+	"maxFiles":     intSchema{min: 0},
+	"concurrency":  intSchema{min: 0},
+	"repositories": listSchema{items: objectSchema{
+		fields: map[string]valueSchema{
+			"repos":    listSchema{items: globSchema},
+			"settings": repositorySettingsSchema(),
+		},
+		required: []string{"repos"},
+	}},
+}}
+
+// repositorySettingsSchema returns the schema of the analyzer configuration,
+// linters of repository profiles have no "exclude" option
+func repositorySettingsSchema() objectSchema {
+	schema := objectSchema{fields: make(map[string]valueSchema)}
+	for name, field := range configSchema.fields {
+		schema.fields[name] = field
+	}
+
+	schema.fields["linters"] = lintersSchema(map[string]valueSchema{
+		enabledOption: boolSchema{},
+	})
+	return schema
+}
+
+// LoadProfile reads the profile from YAML or JSON file, by extension.
+// Options missing in the file are taken from defaults.
+// Error is returned if the file has wrong options.
+func LoadProfile(path string, defaults Profile) (*Profile, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseConfigFile(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	fields := pb.ToStruct(doc).GetFields()
+	if errs := profileSchema.validateFields("", fields); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+
+		return nil, fmt.Errorf("wrong profile %s: %s", path, strings.Join(msgs, "; "))
+	}
+
+	p := defaults
+	if v, ok := fields["linters"]; ok {
+		p.Linters = v
+	}
+
+	if v, ok := fields["deadline"]; ok {
+		p.Deadline, _ = time.ParseDuration(v.GetStringValue())
+	}
+
+	if n, ok := intValue(fields["maxFileSize"]); ok {
+		p.Limits.MaxFileSize = int64(n)
+	}
+
+	if n, ok := intValue(fields["maxTotalSize"]); ok {
+		p.Limits.MaxTotalSize = int64(n)
+	}
+
+	if n, ok := intValue(fields["maxFiles"]); ok {
+		p.Limits.MaxFiles = n
+	}
+
+	if n, ok := intValue(fields["concurrency"]); ok {
+		p.Concurrency = n
+	}
+
+	if v, ok := fields["repositories"]; ok {
+		p.Repositories = nil
+		for _, v := range v.GetListValue().GetValues() {
+			repoFields := v.GetStructValue().GetFields()
+			var r RepositoryProfile
+			for _, glob := range repoFields["repos"].GetListValue().GetValues() {
+				r.Repos = append(r.Repos, glob.GetStringValue())
+			}
+
+			if settings := repoFields["settings"].GetStructValue(); settings != nil {
+				r.Settings = *settings
+			}
+
+			p.Repositories = append(p.Repositories, r)
+		}
+	}
+
+	return &p, nil
+}
+
+// repositoryDefaults returns default settings of linters and the rest of
+// default settings of the repository with the URL. Settings of the matching
+// repository profiles are merged in order over the profile linters.
+func (p *Profile) repositoryDefaults(logger log.Logger, url string) (linterSettings, types.Struct) {
+	linters := readLinterSettings(logger, p.Linters, nil)
+	var settings types.Struct
+	if len(p.Repositories) == 0 {
+		return linters, settings
+	}
+
+	info, err := pb.ParseRepositoryInfo(url)
+	if err != nil {
+		logger.Debugf("no repository profiles for %q: %s", url, err)
+		return linters, settings
+	}
+
+	for _, r := range p.Repositories {
+		if !r.matches(info.FullName) {
+			continue
+		}
+
+		logger.Debugf("repository profile %v is applied to %s", r.Repos, info.FullName)
+		linters = readLinterSettings(logger, r.Settings.GetFields()["linters"], linters)
+		settings = mergeSettings(settings, r.Settings)
+	}
+
+	delete(settings.Fields, "linters")
+	return linters, settings
+}
+
+// mergeSettings returns analyzer settings with options of s overriding
+// the ones of base. Overrides of s are applied after the ones of base.
+func mergeSettings(base, s types.Struct) types.Struct {
+	merged := types.Struct{Fields: make(map[string]*types.Value)}
+	for name, v := range base.GetFields() {
+		merged.Fields[name] = v
+	}
+
+	for name, v := range s.GetFields() {
+		baseList := merged.Fields[name].GetListValue()
+		list := v.GetListValue()
+		if name == "overrides" && baseList != nil && list != nil {
+			values := append(append([]*types.Value(nil), baseList.Values...), list.Values...)
+			v = &types.Value{Kind: &types.Value_ListValue{ListValue: &types.ListValue{Values: values}}}
+		}
+
+		merged.Fields[name] = v
+	}
+
+	return merged
+}
+
+// arguments returns gometalint arguments of every run
+func (p *Profile) arguments() []string {
+	if p.Deadline <= 0 {
+		return nil
+	}
+
+	return []string{"--deadline=" + p.Deadline.String()}
+}
+
+// concurrency returns the number of lint jobs running at the same time
+func (p *Profile) concurrency() int {
+	if p.Concurrency > 0 {
+		return p.Concurrency
+	}
+
+	return runtime.NumCPU()
+}
+
+// ProfileStore holds the current profile. The profile can be replaced
+// while reviews started with the previous one are running.
+type ProfileStore struct {
+	v atomic.Value
+}
+
+// NewProfileStore returns a store with the profile
+func NewProfileStore(p *Profile) *ProfileStore {
+	s := &ProfileStore{}
+	s.Set(p)
+	return s
+}
+
+// Get returns the current profile
+func (s *ProfileStore) Get() *Profile {
+	p, _ := s.v.Load().(*Profile)
+	return p
+}
+
+// Set replaces the current profile
+func (s *ProfileStore) Set(p *Profile) {
+	s.v.Store(p)
+}
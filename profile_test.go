@@ -0,0 +1,196 @@
+package gometalint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+const profileYAML = `linters:
+  - name: lll
+    maxLen: 100
+  - name: gocyclo
+    enabled: false
+deadline: 2m
+maxFiles: 10
+concurrency: 2
+`
+
+func TestLoadProfile(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-profile")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	defaults := Profile{Limits: Limits{MaxFileSize: 1024, MaxFiles: 1000}, Concurrency: 4}
+
+	yamlPath := filepath.Join(dir, "profile.yml")
+	require.NoError(ioutil.WriteFile(yamlPath, []byte(profileYAML), 0644))
+	p, err := LoadProfile(yamlPath, defaults)
+	require.NoError(err)
+	require.Equal(2*time.Minute, p.Deadline)
+	require.Equal(Limits{MaxFileSize: 1024, MaxFiles: 10}, p.Limits)
+	require.Equal(2, p.Concurrency)
+	linters, settings := p.repositoryDefaults(logger, "https://github.com/src-d/lookout")
+	require.Equal([]string{"--disable=gocyclo", "--line-length=100"}, linters.arguments(logger))
+	require.Empty(settings.GetFields())
+	require.Equal([]string{"--deadline=2m0s"}, p.arguments())
+
+	jsonPath := filepath.Join(dir, "profile.json")
+	require.NoError(ioutil.WriteFile(jsonPath, []byte(`{"maxTotalSize": 2048}`), 0644))
+	p, err = LoadProfile(jsonPath, defaults)
+	require.NoError(err)
+	require.Equal(Limits{MaxFileSize: 1024, MaxTotalSize: 2048, MaxFiles: 1000}, p.Limits)
+	require.Equal(4, p.Concurrency)
+	require.Empty(p.arguments())
+
+	require.NoError(ioutil.WriteFile(yamlPath, []byte(`linters:
+  - name: lll
+    maxLen: long
+    exclude: x
+deadline: -1s
+maxFiles: -1
+timeout: 1m
+`), 0644))
+	_, err = LoadProfile(yamlPath, defaults)
+	require.EqualError(err, "wrong profile "+yamlPath+": "+
+		"deadline: must be positive; "+
+		"linters[0].exclude: unknown option; "+
+		"linters[0].maxLen: must be an integer; "+
+		"maxFiles: must be at least 0; "+
+		"timeout: unknown option")
+
+	require.NoError(ioutil.WriteFile(yamlPath, []byte("- a\n"), 0644))
+	_, err = LoadProfile(yamlPath, defaults)
+	require.Error(err)
+
+	_, err = LoadProfile(filepath.Join(dir, "missing.yml"), defaults)
+	require.Error(err)
+}
+
+func TestProfileStore(t *testing.T) {
+	require := require.New(t)
+
+	a := &Analyzer{Limits: Limits{MaxFiles: 5}, Concurrency: 3}
+	require.Equal(&Profile{Limits: Limits{MaxFiles: 5}, Concurrency: 3}, a.profile())
+
+	a.Profile = NewProfileStore(nil)
+	require.Equal(&Profile{Limits: Limits{MaxFiles: 5}, Concurrency: 3}, a.profile(),
+		"analyzer limits must be used without a profile")
+
+	p := &Profile{Linters: pb.ToValue([]map[string]interface{}{{"name": "lll", "maxLen": 90}})}
+	a.Profile.Set(p)
+	require.Equal(p, a.profile())
+}
+
+const repositoriesYAML = `linters:
+  - name: lll
+    maxLen: 100
+repositories:
+  - repos: ["src-d/*"]
+    settings:
+      linters:
+        - name: lll
+          maxLen: 120
+        - name: dupl
+          enabled: false
+      summary: true
+      overrides:
+        - paths: ["cmd/*"]
+          linters:
+            - name: gocyclo
+              enabled: false
+  - repos: ["src-d/lookout", "other/*"]
+    settings:
+      linters:
+        - name: dupl
+          enabled: true
+      summary: false
+      maxComments: 10
+      overrides:
+        - paths: ["pkg/*"]
+          linters:
+            - name: lll
+              enabled: false
+`
+
+func TestRepositoryDefaults(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-profile")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "profile.yml")
+	require.NoError(ioutil.WriteFile(path, []byte(repositoriesYAML), 0644))
+	p, err := LoadProfile(path, Profile{})
+	require.NoError(err)
+	require.Len(p.Repositories, 2)
+	require.Equal([]string{"src-d/lookout", "other/*"}, p.Repositories[1].Repos)
+
+	linters, settings := p.repositoryDefaults(logger, "https://github.com/src-d/lookout.git")
+	require.Equal([]string{"--line-length=120"}, linters.arguments(logger))
+	require.Equal(*pb.ToStruct(map[string]interface{}{
+		"summary":     false,
+		"maxComments": 10,
+		"overrides": []map[string]interface{}{
+			{"paths": []string{"cmd/*"}, "linters": []map[string]interface{}{{"name": "gocyclo", "enabled": false}}},
+			{"paths": []string{"pkg/*"}, "linters": []map[string]interface{}{{"name": "lll", "enabled": false}}},
+		},
+	}), settings)
+
+	linters, settings = p.repositoryDefaults(logger, "https://github.com/src-d/gitbase")
+	require.Equal([]string{"--disable=dupl", "--line-length=120"}, linters.arguments(logger))
+	require.Equal(true, settings.Fields["summary"].GetBoolValue())
+	require.Len(settings.Fields["overrides"].GetListValue().GetValues(), 1)
+
+	for _, url := range []string{"https://github.com/bblfsh/sdk", "file:///src-d/lookout", "not a url"} {
+		linters, settings = p.repositoryDefaults(logger, url)
+		require.Equal([]string{"--line-length=100"}, linters.arguments(logger), url)
+		require.Empty(settings.GetFields(), url)
+	}
+
+	require.NoError(ioutil.WriteFile(path, []byte(`repositories:
+  - settings:
+      linters:
+        - name: lll
+          exclude: x
+      summary: 1
+  - repos: ["["]
+`), 0644))
+	_, err = LoadProfile(path, Profile{})
+	require.EqualError(err, "wrong profile "+path+": "+
+		"repositories[0].repos: is required; "+
+		"repositories[0].settings.linters[0].exclude: unknown option; "+
+		"repositories[0].settings.summary: must be a boolean; "+
+		"repositories[1].repos[0]: syntax error in pattern")
+}
+
+func TestMergeSettings(t *testing.T) {
+	require := require.New(t)
+
+	base := *pb.ToStruct(map[string]interface{}{
+		"summary":   true,
+		"include":   "^pkg/",
+		"overrides": []map[string]interface{}{{"paths": []string{"a"}}},
+	})
+	s := *pb.ToStruct(map[string]interface{}{
+		"summary":   false,
+		"overrides": []map[string]interface{}{{"paths": []string{"b"}}},
+	})
+
+	require.Equal(*pb.ToStruct(map[string]interface{}{
+		"summary":   false,
+		"include":   "^pkg/",
+		"overrides": []map[string]interface{}{{"paths": []string{"a"}}, {"paths": []string{"b"}}},
+	}), mergeSettings(base, s))
+	require.Equal(base, mergeSettings(base, types.Struct{}))
+	require.Equal(s, mergeSettings(types.Struct{}, s))
+}
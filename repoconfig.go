@@ -0,0 +1,453 @@
+package gometalint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// repoConfigFiles are linter configuration files of a repository
+// the analyzer reads, in order of preference
+var repoConfigFiles = []string{".gometalinter.json", ".golangci.yml", ".golangci.yaml"}
+
+// repoConfigPattern matches paths of repoConfigFiles
+var repoConfigPattern = `^(\.gometalinter\.json|\.golangci\.ya?ml)$`
+
+// repoConfig is the subset of a repository linter configuration file
+// translated to the analyzer options
+type repoConfig struct {
+	file    string
+	linters linterSettings
+	// skipDirs match directories of files not to analyze
+	skipDirs []*regexp.Regexp
+	// skipFiles match paths of files not to analyze
+	skipFiles []*regexp.Regexp
+	// excludeIssues match messages of issues not to report
+	excludeIssues []*regexp.Regexp
+	// ignored are options of the file that are not supported or wrong
+	ignored []ignoredOption
+	// err is the error of parsing the file, all options are ignored then
+	err error
+}
+
+// ignoredOption is an option of the configuration file that is ignored
+type ignoredOption struct {
+	key    string
+	reason string
+}
+
+// fetchRepoConfig reads the linter configuration file of the repository at
+// the revision, nil is returned if there is no such file or it can't be read
+func fetchRepoConfig(ctx context.Context, logger log.Logger, client pb.DataClient,
+	rev *pb.ReferencePointer) *repoConfig {
+
+	stream, err := client.GetFiles(ctx, &pb.FilesRequest{
+		Revision:       rev,
+		IncludePattern: repoConfigPattern,
+		WantContents:   true,
+	})
+	if err != nil {
+		logger.Warningf("failed to get linter configuration from DataService: %s", err)
+		return nil
+	}
+
+	files := make(map[string]*pb.File)
+	for {
+		file, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			logger.Warningf("failed to get linter configuration from DataService: %s", err)
+			return nil
+		}
+
+		files[file.Path] = file
+	}
+
+	for _, name := range repoConfigFiles {
+		file, ok := files[name]
+		if !ok {
+			continue
+		}
+
+		logger.Debugf("using linter configuration from %s", name)
+		c, err := parseRepoConfig(name, file.Content)
+		if err != nil {
+			logger.Warningf("wrong linter configuration in %s: %s", name, err)
+			return &repoConfig{file: name, err: err}
+		}
+
+		return c
+	}
+
+	return nil
+}
+
+// parseRepoConfig translates content of the configuration file with the name
+func parseRepoConfig(name string, content []byte) (*repoConfig, error) {
+	doc, err := parseConfigFile(name, content)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &repoConfig{file: name, linters: make(linterSettings)}
+	if name == ".gometalinter.json" {
+		c.readGometalinter(doc)
+	} else {
+		c.readGolangci(doc)
+	}
+
+	sort.SliceStable(c.ignored, func(i, j int) bool { return c.ignored[i].key < c.ignored[j].key })
+	return c, nil
+}
+
+// parseConfigFile decodes content of JSON or YAML file, by extension of the name
+func parseConfigFile(name string, content []byte) (map[string]interface{}, error) {
+	if path.Ext(name) == ".json" {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, err
+		}
+
+		return doc, nil
+	}
+
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	doc, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok && raw != nil {
+		return nil, fmt.Errorf("configuration must be a mapping")
+	}
+
+	return doc, nil
+}
+
+// normalizeYAML converts mappings decoded by yaml to map[string]interface{}
+// the same way encoding/json decodes objects
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = normalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalizeYAML(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// gometalinterKeys are the supported options of .gometalinter.json
+var gometalinterKeys = []string{
+	"Enable", "Disable", "EnableAll", "DisableAll",
+	"LineLength", "Cyclo", "Exclude", "Skip",
+}
+
+// readGometalinter reads options of gometalinter configuration,
+// see https://github.com/alecthomas/gometalinter#configuration-file
+func (c *repoConfig) readGometalinter(doc map[string]interface{}) {
+	c.ignoreUnsupported(doc, "", gometalinterKeys)
+
+	c.readLinters(doc, "Enable", "Disable", "EnableAll", "DisableAll")
+	c.readOption(doc, "LineLength", "lll", "maxLen")
+	c.readOption(doc, "Cyclo", "gocyclo", "over")
+	c.excludeIssues = c.readRegexps(doc, "Exclude")
+
+	for _, dir := range c.readStrings(doc, "Skip") {
+		re := regexp.MustCompile("^" + regexp.QuoteMeta(path.Clean(dir)) + "$")
+		c.skipDirs = append(c.skipDirs, re)
+	}
+}
+
+// golangciKeys are the supported options of .golangci.yml
+var golangciKeys = []string{
+	"linters.enable", "linters.disable", "linters.enable-all", "linters.disable-all",
+	"linters-settings.lll.line-length", "linters-settings.gocyclo.min-complexity",
+	"issues.exclude", "run.skip-dirs", "run.skip-files",
+}
+
+// readGolangci reads options of golangci-lint configuration,
+// see https://github.com/golangci/golangci-lint#config-file
+func (c *repoConfig) readGolangci(doc map[string]interface{}) {
+	c.ignoreUnsupported(doc, "", golangciKeys)
+
+	linters := mapValue(doc["linters"])
+	c.readLinters(linters, "linters.enable", "linters.disable", "linters.enable-all", "linters.disable-all")
+
+	settings := mapValue(doc["linters-settings"])
+	c.readOption(mapValue(settings["lll"]), "linters-settings.lll.line-length", "lll", "maxLen")
+	c.readOption(mapValue(settings["gocyclo"]), "linters-settings.gocyclo.min-complexity", "gocyclo", "over")
+
+	c.excludeIssues = c.readRegexps(mapValue(doc["issues"]), "issues.exclude")
+
+	run := mapValue(doc["run"])
+	c.skipDirs = c.readRegexps(run, "run.skip-dirs")
+	c.skipFiles = c.readRegexps(run, "run.skip-files")
+}
+
+// mapValue returns v if it's a mapping, nil otherwise
+func mapValue(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// lastKey returns the last part of the dot-separated key
+func lastKey(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
+
+// ignoreUnsupported marks options under the prefix that are not in supported
+// as ignored, the keys are dot-separated paths of options
+func (c *repoConfig) ignoreUnsupported(doc map[string]interface{}, prefix string, supported []string) {
+	for k, v := range doc {
+		key := prefix + k
+
+		isSupported, isParent := false, false
+		for _, s := range supported {
+			isSupported = isSupported || s == key
+			isParent = isParent || strings.HasPrefix(s, key+".")
+		}
+
+		switch {
+		case isSupported:
+		case isParent && mapValue(v) != nil:
+			c.ignoreUnsupported(mapValue(v), key+".", supported)
+		default:
+			c.ignore(key, "not supported")
+		}
+	}
+}
+
+// ignore marks the option as ignored for the reason
+func (c *repoConfig) ignore(key, reason string) {
+	c.ignored = append(c.ignored, ignoredOption{key: key, reason: reason})
+}
+
+// readStrings returns the list of strings of the option
+func (c *repoConfig) readStrings(doc map[string]interface{}, key string) []string {
+	v, ok := doc[lastKey(key)]
+	if !ok || v == nil {
+		return nil
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		c.ignore(key, "must be a list of strings")
+		return nil
+	}
+
+	var strs []string
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			c.ignore(key, "must be a list of strings")
+			return nil
+		}
+
+		strs = append(strs, s)
+	}
+
+	return strs
+}
+
+// readRegexps returns compiled regexps of the option, wrong ones are ignored
+func (c *repoConfig) readRegexps(doc map[string]interface{}, key string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, s := range c.readStrings(doc, key) {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			c.ignore(key, fmt.Sprintf("wrong regexp %q: %s", s, err))
+			continue
+		}
+
+		res = append(res, re)
+	}
+
+	return res
+}
+
+// readLinters reads lists of enabled and disabled linters. Linters that are
+// not enabled are disabled only if all linters are disabled by the config,
+// all linters are enabled if the config enables all, before the disabled ones
+// are applied.
+func (c *repoConfig) readLinters(doc map[string]interface{},
+	enableKey, disableKey, enableAllKey, disableAllKey string) {
+
+	enabled := make(map[string]bool)
+	var unsupported []string
+	for _, name := range c.readStrings(doc, enableKey) {
+		if !isKnownLinter(name) {
+			unsupported = append(unsupported, "`"+name+"`")
+			continue
+		}
+
+		enabled[name] = true
+		c.setOption(name, enabledOption, &types.Value{Kind: &types.Value_BoolValue{BoolValue: true}})
+	}
+
+	if len(unsupported) > 0 {
+		c.ignore(enableKey, "linters "+strings.Join(unsupported, ", ")+" are not supported")
+	}
+
+	enableAll := c.readBool(doc, enableAllKey)
+	disableAll := c.readBool(doc, disableAllKey)
+	switch {
+	case enableAll && disableAll:
+		c.ignore(enableAllKey, "can't be combined with "+lastKey(disableAllKey))
+		c.ignore(disableAllKey, "can't be combined with "+lastKey(enableAllKey))
+	case enableAll:
+		for _, name := range knownLinters() {
+			if !enabled[name] {
+				c.setOption(name, enabledOption, &types.Value{Kind: &types.Value_BoolValue{BoolValue: true}})
+			}
+		}
+	case disableAll:
+		for _, name := range knownLinters() {
+			if !enabled[name] {
+				c.disable(name)
+			}
+		}
+	}
+
+	for _, name := range c.readStrings(doc, disableKey) {
+		if isKnownLinter(name) {
+			c.disable(name)
+		}
+	}
+}
+
+// readBool returns the boolean option, false if it's not set or wrong
+func (c *repoConfig) readBool(doc map[string]interface{}, key string) bool {
+	v, ok := doc[lastKey(key)]
+	if !ok || v == nil {
+		return false
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		c.ignore(key, "must be a boolean")
+	}
+
+	return b
+}
+
+// disable sets the linter to be disabled
+func (c *repoConfig) disable(name string) {
+	c.setOption(name, enabledOption, &types.Value{Kind: &types.Value_BoolValue{BoolValue: false}})
+}
+
+// readOption reads the number option of the linter
+func (c *repoConfig) readOption(doc map[string]interface{}, key, linter, option string) {
+	v, ok := doc[lastKey(key)]
+	if !ok || v == nil {
+		return
+	}
+
+	var n float64
+	switch v := v.(type) {
+	case float64:
+		n = v
+	case int:
+		n = float64(v)
+	default:
+		c.ignore(key, "must be a number")
+		return
+	}
+
+	c.setOption(linter, option, &types.Value{Kind: &types.Value_NumberValue{NumberValue: n}})
+}
+
+// setOption sets the option of the linter
+func (c *repoConfig) setOption(linter, option string, v *types.Value) {
+	c.linters = c.linters.merge(linterSettings{linter: {option: v}})
+}
+
+// linterSettings returns options of linters of the configuration
+func (c *repoConfig) linterSettings() linterSettings {
+	if c == nil {
+		return nil
+	}
+
+	return c.linters
+}
+
+// skipFile returns true if the file must not be analyzed
+func (c *repoConfig) skipFile(p string) bool {
+	if c == nil {
+		return false
+	}
+
+	for _, re := range c.skipFiles {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+
+	for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		for _, re := range c.skipDirs {
+			if re.MatchString(dir) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// keepComment returns true if the comment is not excluded
+func (c *repoConfig) keepComment(comment Comment) bool {
+	if c == nil {
+		return true
+	}
+
+	for _, re := range c.excludeIssues {
+		if re.MatchString(comment.Message()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ignoredComment returns the global comment listing ignored options of the
+// configuration file, nil if all options are supported
+func (c *repoConfig) ignoredComment() *pb.Comment {
+	if c == nil {
+		return nil
+	}
+
+	if c.err != nil {
+		return &pb.Comment{Text: fmt.Sprintf("`%s` is ignored: %s", c.file, c.err)}
+	}
+
+	if len(c.ignored) == 0 {
+		return nil
+	}
+
+	text := fmt.Sprintf("Some options of `%s` are ignored:", c.file)
+	for _, o := range c.ignored {
+		text += fmt.Sprintf("\n- `%s`: %s", o.key, o.reason)
+	}
+
+	return &pb.Comment{Text: text}
+}
@@ -0,0 +1,169 @@
+package gometalint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/lookout-gometalint-analyzer/datatest"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+const gometalinterConfig = `{
+	"DisableAll": true,
+	"Enable": ["lll", "gocyclo", "vet", "golint"],
+	"LineLength": 120,
+	"Cyclo": 15,
+	"Exclude": ["should have comment", "("],
+	"Skip": ["internal/legacy/"],
+	"Deadline": "5m"
+}`
+
+func TestParseGometalinterConfig(t *testing.T) {
+	require := require.New(t)
+
+	c, err := parseRepoConfig(".gometalinter.json", []byte(gometalinterConfig))
+	require.NoError(err)
+	require.Equal([]string{
+		"--disable=dupl", "--cyclo-over=15", "--disable=gofmt", "--disable=goimports",
+		"--disable=gosec", "--line-length=120", "--disable=misspell",
+	}, c.linterSettings().arguments(logger))
+
+	require.True(c.skipFile("internal/legacy/a.go"))
+	require.True(c.skipFile("internal/legacy/sub/a.go"))
+	require.False(c.skipFile("internal/a.go"))
+	require.False(c.skipFile("legacy/a.go"))
+
+	require.False(c.keepComment(NewComment("warning", "a.go", 1, 0, "exported X should have comment (golint)")))
+	require.True(c.keepComment(NewComment("warning", "a.go", 1, 0, "long line (lll)")))
+
+	require.Equal(&pb.Comment{Text: "Some options of `.gometalinter.json` are ignored:\n" +
+		"- `Deadline`: not supported\n" +
+		"- `Enable`: linters `vet`, `golint` are not supported\n" +
+		"- `Exclude`: wrong regexp \"(\": error parsing regexp: missing closing ): `(`"},
+		c.ignoredComment())
+}
+
+const golangciConfig = `
+run:
+  deadline: 5m
+  skip-dirs:
+    - testdata$
+  skip-files:
+    - _gen\.go$
+linters:
+  enable:
+    - lll
+  disable:
+    - gosec
+    - errcheck
+linters-settings:
+  lll:
+    line-length: 100
+  gocyclo:
+    min-complexity: twenty
+  govet:
+    check-shadowing: true
+issues:
+  exclude:
+    - G104
+`
+
+func TestParseGolangciConfig(t *testing.T) {
+	require := require.New(t)
+
+	c, err := parseRepoConfig(".golangci.yml", []byte(golangciConfig))
+	require.NoError(err)
+	require.Equal([]string{"--disable=gosec", "--line-length=100"},
+		c.linterSettings().arguments(logger))
+
+	require.True(c.skipFile("pkg/testdata/a.go"))
+	require.True(c.skipFile("pkg/testdata/sub/a.go"))
+	require.True(c.skipFile("pkg/a_gen.go"))
+	require.False(c.skipFile("pkg/a.go"))
+
+	require.False(c.keepComment(NewComment("warning", "a.go", 1, 0, "G104: errors unhandled (gosec)")))
+
+	require.Equal(&pb.Comment{Text: "Some options of `.golangci.yml` are ignored:\n" +
+		"- `linters-settings.gocyclo.min-complexity`: must be a number\n" +
+		"- `linters-settings.govet`: not supported\n" +
+		"- `run.deadline`: not supported"},
+		c.ignoredComment())
+}
+
+func TestParseEnableAll(t *testing.T) {
+	require := require.New(t)
+
+	configs := []struct{ name, content string }{
+		{".gometalinter.json", `{"EnableAll": true, "Disable": ["lll"]}`},
+		{".golangci.yml", "linters:\n  enable-all: true\n  disable:\n    - lll\n"},
+	}
+
+	for _, conf := range configs {
+		c, err := parseRepoConfig(conf.name, []byte(conf.content))
+		require.NoError(err)
+		require.Nil(c.ignoredComment(), conf.name)
+		require.Equal([]string{"--disable=lll"}, c.linterSettings().arguments(logger), conf.name)
+
+		for _, name := range knownLinters() {
+			require.Equal(name != "lll", c.linterSettings().enabled(logger, name), "%s: %s", conf.name, name)
+		}
+	}
+
+	c, err := parseRepoConfig(".golangci.yml", []byte("linters:\n  enable-all: true\n  disable-all: true\n"))
+	require.NoError(err)
+	require.Empty(c.linterSettings().arguments(logger))
+	require.Equal(&pb.Comment{Text: "Some options of `.golangci.yml` are ignored:\n" +
+		"- `linters.disable-all`: can't be combined with enable-all\n" +
+		"- `linters.enable-all`: can't be combined with disable-all"},
+		c.ignoredComment())
+}
+
+func TestParseRepoConfigErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := parseRepoConfig(".gometalinter.json", []byte(`{"Enable": `))
+	require.Error(err)
+
+	_, err = parseRepoConfig(".golangci.yml", []byte("- lll\n"))
+	require.EqualError(err, "configuration must be a mapping")
+
+	c, err := parseRepoConfig(".golangci.yml", []byte(""))
+	require.NoError(err)
+	require.Nil(c.ignoredComment())
+	require.Empty(c.linterSettings().arguments(logger))
+
+	c, err = parseRepoConfig(".golangci.yml", []byte("linters:\n  enable: lll\n  disable-all: yes please\n"))
+	require.NoError(err)
+	require.Equal(&pb.Comment{Text: "Some options of `.golangci.yml` are ignored:\n" +
+		"- `linters.disable-all`: must be a boolean\n" +
+		"- `linters.enable`: must be a list of strings"},
+		c.ignoredComment())
+}
+
+func TestFetchRepoConfig(t *testing.T) {
+	require := require.New(t)
+
+	client := datatest.NewDataClient()
+	require.Nil(fetchRepoConfig(context.Background(), logger, client, &pb.ReferencePointer{}))
+
+	client.Files = []*pb.File{
+		datatest.File("sub/.gometalinter.json", `{"Cyclo": 5}`),
+		datatest.File(".golangci.yml", "linters:\n  disable: [lll]\n"),
+		datatest.File(".gometalinter.json", `{"LineLength": 80}`),
+	}
+	head := &pb.ReferencePointer{InternalRepositoryURL: "file:///repo", Hash: "head"}
+	c := fetchRepoConfig(context.Background(), logger, client, head)
+	require.Equal(".gometalinter.json", c.file)
+	require.Equal([]string{"--line-length=80"}, c.linterSettings().arguments(logger))
+
+	reqs := client.FilesRequests()
+	require.Equal(head, reqs[len(reqs)-1].Revision)
+	require.True(reqs[len(reqs)-1].WantContents)
+
+	client.Files = []*pb.File{datatest.File(".golangci.yml", "linters: [")}
+	require.Equal(&pb.Comment{
+		Text: "`.golangci.yml` is ignored: yaml: line 1: did not find expected node content",
+	}, fetchRepoConfig(context.Background(), logger, client, head).ignoredComment())
+}
@@ -0,0 +1,69 @@
+package gometalint
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultSandboxEnv is the list of environment variables passed to linters
+// running in a sandbox if no other is configured
+var DefaultSandboxEnv = []string{"PATH", "GOPATH", "GOROOT", "LANG", "LC_ALL"}
+
+// Sandbox restricts resources and privileges of linter processes.
+// Limits and namespaces are supported only on Linux.
+// The current binary is re-executed to prepare the sandbox,
+// so it must call SandboxMain at the top of main.
+type Sandbox struct {
+	// CPUTime limits CPU time of every linter process, 0 for no limit
+	CPUTime time.Duration
+	// Memory limits address space of every linter process in bytes, 0 for no limit
+	Memory uint64
+	// OpenFiles limits number of open files of every linter process, 0 for no limit
+	OpenFiles uint64
+	// Env is the list of environment variables passed to linters,
+	// the rest of environment is scrubbed
+	Env []string
+	// NoNetwork runs linters in a new network namespace without interfaces,
+	// ignored if namespaces are not available
+	NoNetwork bool
+	// ReadOnly makes the whole file system read-only for linters
+	// except for the analyzed files and a private temporary directory,
+	// ignored if namespaces are not available
+	ReadOnly bool
+}
+
+// environ returns the scrubbed environment with the given variables added,
+// they replace the variables with the same names
+func (s *Sandbox) environ(extra ...string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name := envName(kv)
+		if !containsString(s.Env, name) {
+			continue
+		}
+
+		replaced := false
+		for _, e := range extra {
+			if envName(e) == name {
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			env = append(env, kv)
+		}
+	}
+
+	return append(env, extra...)
+}
+
+// envName returns the name of the variable of "NAME=value" string
+func envName(kv string) string {
+	if i := strings.IndexByte(kv, '='); i >= 0 {
+		return kv[:i]
+	}
+
+	return kv
+}
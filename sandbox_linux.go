@@ -0,0 +1,317 @@
+package gometalint
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "gopkg.in/src-d/go-log.v1"
+)
+
+// sandboxInitEnv is set for the binary re-executed to prepare the sandbox,
+// it contains JSON encoded sandboxSpec
+const sandboxInitEnv = "GOMETALINT_SANDBOX_INIT"
+
+// sandboxFailed is the exit code of the sandbox init if it fails
+const sandboxFailed = 126
+
+// sandboxSpec describes what sandbox init must do before executing a linter
+type sandboxSpec struct {
+	CPUTime   uint64   `json:"cpu_time"`
+	Memory    uint64   `json:"memory"`
+	OpenFiles uint64   `json:"open_files"`
+	ReadOnly  bool     `json:"read_only"`
+	Writable  []string `json:"writable"`
+	Path      string   `json:"path"`
+	Args      []string `json:"args"`
+	Env       []string `json:"env"`
+}
+
+// SandboxMain takes over the process if it was re-executed as a sandbox init.
+// It must be called at the top of main of binaries running linters in Sandbox,
+// the process is never returned to the caller in this case.
+func SandboxMain() {
+	spec := os.Getenv(sandboxInitEnv)
+	if spec == "" {
+		return
+	}
+
+	err := sandboxExec(spec)
+	fmt.Fprintf(os.Stderr, "sandbox: %s\n", err)
+	os.Exit(sandboxFailed)
+}
+
+// output runs the command in the sandbox and returns its standard output.
+// Linters can write only to the given paths and to a private temporary directory.
+// If namespaces are not available, the command runs only with limits applied.
+// The command is killed if the context is done.
+func (s *Sandbox) output(ctx context.Context, name string, args []string, writable []string,
+	env []string, stderr io.Writer) ([]byte, error) {
+
+	tmp, err := ioutil.TempDir("", "gometalint-sandbox")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	cmd, err := s.command(ctx, name, args, env, append(writable, tmp), tmp, true)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if !isNamespaceError(err) {
+		return out, err
+	}
+
+	log.Warningf("namespaces are not available, running linters only with limits: %s", err)
+	if cmd, err = s.command(ctx, name, args, env, nil, tmp, false); err != nil {
+		return nil, err
+	}
+
+	cmd.Stderr = stderr
+	return cmd.Output()
+}
+
+// command returns a command re-executing the current binary as sandbox init,
+// the environment variables are added to the scrubbed environment
+func (s *Sandbox) command(ctx context.Context, name string, args []string, env []string, writable []string,
+	tmp string, namespaces bool) (*exec.Cmd, error) {
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := sandboxSpec{
+		CPUTime:   uint64(s.CPUTime.Seconds()),
+		Memory:    s.Memory,
+		OpenFiles: s.OpenFiles,
+		ReadOnly:  s.ReadOnly && namespaces,
+		Path:      path,
+		Args:      append([]string{name}, args...),
+		Env:       s.environ(append(append([]string(nil), env...), "TMPDIR="+tmp, "GOCACHE="+filepath.Join(tmp, "go-build"))...),
+	}
+
+	if s.CPUTime > 0 && spec.CPUTime == 0 {
+		spec.CPUTime = 1
+	}
+
+	for _, w := range writable {
+		real, err := filepath.EvalSymlinks(w)
+		if err != nil {
+			return nil, err
+		}
+
+		spec.Writable = append(spec.Writable, real)
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, self) // nolint: gas
+	cmd.Env = []string{sandboxInitEnv + "=" + string(data)}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+
+	if !namespaces || (!s.ReadOnly && !s.NoNetwork) {
+		return cmd, nil
+	}
+
+	// map the current user to root of the new user namespace,
+	// so the init keeps capabilities needed to remount file systems
+	cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	if s.ReadOnly {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNS
+	}
+
+	if s.NoNetwork {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+
+	return cmd, nil
+}
+
+// isNamespaceError returns true if the process couldn't be started
+// because creating namespaces is not permitted
+func isNamespaceError(err error) bool {
+	pe, ok := err.(*os.PathError)
+	if !ok {
+		return false
+	}
+
+	switch pe.Err {
+	case syscall.EPERM, syscall.EINVAL, syscall.ENOSPC, syscall.EUSERS:
+		return true
+	default:
+		return false
+	}
+}
+
+// sandboxExec applies the spec to the current process and executes the linter
+func sandboxExec(data string) error {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(data), &spec); err != nil {
+		return err
+	}
+
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, spec.CPUTime},
+		{syscall.RLIMIT_AS, spec.Memory},
+		{syscall.RLIMIT_NOFILE, spec.OpenFiles},
+	}
+
+	for _, l := range limits {
+		if l.value == 0 {
+			continue
+		}
+
+		rlimit := &syscall.Rlimit{Cur: l.value, Max: l.value}
+		if err := syscall.Setrlimit(l.resource, rlimit); err != nil {
+			return fmt.Errorf("cannot set limit %d: %s", l.resource, err)
+		}
+	}
+
+	if spec.ReadOnly {
+		if err := mountReadOnly(spec.Writable); err != nil {
+			return err
+		}
+	}
+
+	return syscall.Exec(spec.Path, spec.Args, spec.Env)
+}
+
+// mountReadOnly remounts all file systems read-only except for the writable paths.
+// It must be called in a new mount namespace.
+func mountReadOnly(writable []string) error {
+	err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, "")
+	if err != nil {
+		return fmt.Errorf("cannot make mounts private: %s", err)
+	}
+
+	// writable paths become separate mount points, so they can be skipped
+	for _, w := range writable {
+		err := syscall.Mount(w, w, "", syscall.MS_BIND|syscall.MS_REC, "")
+		if err != nil {
+			return fmt.Errorf("cannot bind %s: %s", w, err)
+		}
+	}
+
+	mounts, err := readMountInfo()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mounts {
+		if isUnderAny(m.point, writable) {
+			continue
+		}
+
+		flags := syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | m.flags
+		err := syscall.Mount("", m.point, "", uintptr(flags), "")
+		if err == syscall.EACCES || err == syscall.ENOENT {
+			// not accessible for linters anyway
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("cannot remount %s read-only: %s", m.point, err)
+		}
+	}
+
+	return nil
+}
+
+type mountInfo struct {
+	point string
+	// flags are per-mount flags that must be preserved on remount
+	flags int
+}
+
+var mountFlags = map[string]int{
+	"nosuid":      syscall.MS_NOSUID,
+	"nodev":       syscall.MS_NODEV,
+	"noexec":      syscall.MS_NOEXEC,
+	"noatime":     syscall.MS_NOATIME,
+	"nodiratime":  syscall.MS_NODIRATIME,
+	"relatime":    syscall.MS_RELATIME,
+	"strictatime": syscall.MS_STRICTATIME,
+}
+
+// readMountInfo returns mount points of the current mount namespace
+func readMountInfo() ([]mountInfo, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountInfo
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// id parent major:minor root mount-point options ...
+		fields := strings.Fields(s.Text())
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("unexpected mountinfo line %q", s.Text())
+		}
+
+		m := mountInfo{point: unescapeMountPoint(fields[4])}
+		for _, opt := range strings.Split(fields[5], ",") {
+			m.flags |= mountFlags[opt]
+		}
+
+		mounts = append(mounts, m)
+	}
+
+	return mounts, s.Err()
+}
+
+// unescapeMountPoint decodes octal escapes like \040 used in mountinfo
+func unescapeMountPoint(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// isUnderAny returns true if path is equal to or inside any of dirs
+func isUnderAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+package gometalint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/src-d/lookout-gometalint-analyzer/datatest"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// TestMain lets the test binary be re-executed as a sandbox init
+func TestMain(m *testing.M) {
+	SandboxMain()
+	os.Exit(m.Run())
+}
+
+// runInSandbox runs shell script in the sandbox with dir writable
+func runInSandbox(t *testing.T, s *Sandbox, dir, script string) (string, error) {
+	var stderr bytes.Buffer
+	out, err := s.output(context.Background(), "sh", []string{"-c", script}, []string{dir}, nil, &stderr)
+	if err != nil {
+		t.Logf("stderr: %s", stderr.String())
+	}
+
+	return strings.TrimSpace(string(out)), err
+}
+
+func TestSandboxLimits(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-sandbox-test")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	s := &Sandbox{
+		CPUTime:   3 * time.Second,
+		Memory:    1 << 30,
+		OpenFiles: 64,
+	}
+
+	out, err := runInSandbox(t, s, dir, "ulimit -t; ulimit -v; ulimit -n")
+	require.NoError(err)
+	require.Equal("3\n1048576\n64", out)
+}
+
+func TestSandboxEnv(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-sandbox-test")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("GOMETALINT_SECRET", "secret")
+	defer os.Unsetenv("GOMETALINT_SECRET")
+
+	s := &Sandbox{Env: DefaultSandboxEnv}
+	out, err := runInSandbox(t, s, dir, "echo \"$PATH\"; echo \"$TMPDIR\"; echo \"secret:$GOMETALINT_SECRET\"")
+	require.NoError(err)
+
+	lines := strings.Split(out, "\n")
+	require.Len(lines, 3)
+	require.Equal(os.Getenv("PATH"), lines[0])
+	require.NotEmpty(lines[1])
+	require.Equal("secret:", lines[2], "not allowed variables must be scrubbed")
+
+	require.Equal([]string{"PATH=/bin", "GO111MODULE=off"},
+		(&Sandbox{Env: []string{"PATH"}}).environ("PATH=/bin", "GO111MODULE=off"),
+		"added variables must replace the allowed ones")
+}
+
+func TestSandboxNamespaces(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gometalint-sandbox-test")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	s := &Sandbox{Env: DefaultSandboxEnv, NoNetwork: true, ReadOnly: true}
+
+	var stderr bytes.Buffer
+	cmd, err := s.command(context.Background(), "true", nil, nil, []string{dir}, dir, true)
+	require.NoError(err)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); isNamespaceError(err) {
+		t.Skipf("namespaces are not available: %s", err)
+	}
+
+	out, err := runInSandbox(t, s, dir, fmt.Sprintf(`
+echo ok > '%[1]s/written' && echo written
+echo ok > "$TMPDIR/tmp" && echo tmp
+touch '%[1]s/../gometalint-escape' 2>/dev/null || echo read-only
+grep -c : /proc/net/dev
+`, dir))
+	require.NoError(err)
+	// /proc/net/dev has 2 header lines, only loopback interface must be present
+	require.Equal("written\ntmp\nread-only\n1", out)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "written"))
+	require.NoError(err)
+	require.Equal("ok\n", string(content))
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(dir), "gometalint-escape"))
+	require.True(os.IsNotExist(err))
+}
+
+func TestSandboxCPULimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gometalint-sandbox-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := &Sandbox{CPUTime: time.Second}
+	_, err = runInSandbox(t, s, dir, "while true; do :; done")
+	require.Error(t, err)
+}
+
+func TestMountInfo(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("/mnt/with space", unescapeMountPoint(`/mnt/with\040space`))
+	require.Equal(`/a\b`, unescapeMountPoint(`/a\b`))
+
+	require.True(isUnderAny("/tmp/a", []string{"/tmp/a"}))
+	require.True(isUnderAny("/tmp/a/b", []string{"/tmp/a"}))
+	require.False(isUnderAny("/tmp/ab", []string{"/tmp/a"}))
+
+	mounts, err := readMountInfo()
+	require.NoError(err)
+	require.NotEmpty(mounts)
+}
+
+func TestReviewInSandbox(t *testing.T) {
+	require := require.New(t)
+	defer withFakeLinter(t, fakeLinter)()
+
+	a := &Analyzer{
+		DataClient: datatest.NewDataClient(fixtureChanges(t, "gofmt_test.go")...),
+		Sandbox:    &Sandbox{Env: DefaultSandboxEnv, NoNetwork: true, ReadOnly: true},
+	}
+
+	resp, err := a.NotifyReviewEvent(context.Background(), &pb.ReviewEvent{})
+	require.NoError(err)
+	require.Equal([]*pb.Comment{
+		{File: "gofmt_test.go", Line: 1, Text: "issue in gofmt_test.go (fake)"},
+	}, resp.Comments)
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package gometalint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// SandboxMain does nothing, sandbox is not supported on this platform
+func SandboxMain() {}
+
+// output returns error, sandbox is not supported on this platform
+func (s *Sandbox) output(ctx context.Context, name string, args []string, writable []string,
+	env []string, stderr io.Writer) ([]byte, error) {
+
+	return nil, fmt.Errorf("sandbox is not supported on %s", runtime.GOOS)
+}
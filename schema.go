@@ -0,0 +1,323 @@
+package gometalint
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	types "github.com/gogo/protobuf/types"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// ConfigError is a problem of the configuration value at the path
+type ConfigError struct {
+	// Path of the value, e.g. "linters[0].maxLen"
+	Path    string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return e.Path + ": " + e.Message
+}
+
+// valueSchema validates a value of the configuration
+type valueSchema interface {
+	validate(path string, v *types.Value) []ConfigError
+}
+
+// childPath returns path of the field of an object at the path
+func childPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+
+	return path + "." + field
+}
+
+// boolSchema accepts booleans
+type boolSchema struct{}
+
+func (boolSchema) validate(path string, v *types.Value) []ConfigError {
+	if _, ok := v.GetKind().(*types.Value_BoolValue); !ok {
+		return []ConfigError{{path, "must be a boolean"}}
+	}
+
+	return nil
+}
+
+// intSchema accepts integer numbers and strings with them not less than min
+type intSchema struct {
+	min int
+}
+
+func (s intSchema) validate(path string, v *types.Value) []ConfigError {
+	n, ok := intValue(v)
+	if !ok {
+		return []ConfigError{{path, "must be an integer"}}
+	}
+
+	if n < s.min {
+		return []ConfigError{{path, fmt.Sprintf("must be at least %d", s.min)}}
+	}
+
+	return nil
+}
+
+// numberSchema accepts numbers and strings with them
+type numberSchema struct{}
+
+func (numberSchema) validate(path string, v *types.Value) []ConfigError {
+	if _, ok := numberValue(v); !ok {
+		return []ConfigError{{path, "must be a number"}}
+	}
+
+	return nil
+}
+
+// stringSchema accepts strings passing the check, if it's not nil
+type stringSchema struct {
+	check func(string) error
+}
+
+func (s stringSchema) validate(path string, v *types.Value) []ConfigError {
+	sv, ok := v.GetKind().(*types.Value_StringValue)
+	if !ok {
+		return []ConfigError{{path, "must be a string"}}
+	}
+
+	if s.check == nil {
+		return nil
+	}
+
+	if err := s.check(sv.StringValue); err != nil {
+		return []ConfigError{{path, err.Error()}}
+	}
+
+	return nil
+}
+
+// regexpSchema accepts valid regular expressions
+var regexpSchema = stringSchema{check: func(s string) error {
+	_, err := regexp.Compile(s)
+	return err
+}}
+
+// globSchema accepts valid non-empty globs
+var globSchema = stringSchema{check: func(s string) error {
+	if s == "" {
+		return fmt.Errorf("must be a non-empty glob")
+	}
+
+	_, err := path.Match(s, "")
+	return err
+}}
+
+// durationSchema accepts positive durations, e.g. "5m"
+var durationSchema = stringSchema{check: func(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	if d <= 0 {
+		return fmt.Errorf("must be positive")
+	}
+
+	return nil
+}}
+
+// enumSchema accepts one of the strings
+type enumSchema []string
+
+func (s enumSchema) validate(path string, v *types.Value) []ConfigError {
+	str := v.GetStringValue()
+	for _, allowed := range s {
+		if str == allowed {
+			return nil
+		}
+	}
+
+	return []ConfigError{{path, fmt.Sprintf("must be one of %s", quoteList(s))}}
+}
+
+// quoteList joins the strings in quotes
+func quoteList(strs []string) string {
+	quoted := make([]string, len(strs))
+	for i, s := range strs {
+		quoted[i] = strconv.Quote(s)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// listSchema accepts lists of values accepted by items
+type listSchema struct {
+	items valueSchema
+}
+
+func (s listSchema) validate(path string, v *types.Value) []ConfigError {
+	list, ok := v.GetKind().(*types.Value_ListValue)
+	if !ok {
+		return []ConfigError{{path, "must be a list"}}
+	}
+
+	var errs []ConfigError
+	for i, item := range list.ListValue.GetValues() {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if item == nil {
+			errs = append(errs, ConfigError{itemPath, "must not be empty"})
+			continue
+		}
+
+		errs = append(errs, s.items.validate(itemPath, item)...)
+	}
+
+	return errs
+}
+
+// objectSchema accepts objects with the known fields only
+type objectSchema struct {
+	fields   map[string]valueSchema
+	required []string
+}
+
+func (s objectSchema) validate(path string, v *types.Value) []ConfigError {
+	sv, ok := v.GetKind().(*types.Value_StructValue)
+	if !ok {
+		return []ConfigError{{path, "must be an object"}}
+	}
+
+	return s.validateFields(path, sv.StructValue.GetFields())
+}
+
+// validateFields validates fields of an object at the path
+func (s objectSchema) validateFields(path string, fields map[string]*types.Value) []ConfigError {
+	var errs []ConfigError
+	for _, name := range s.required {
+		if fields[name] == nil {
+			errs = append(errs, ConfigError{childPath(path, name), "is required"})
+		}
+	}
+
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v := fields[name]
+		if v == nil {
+			continue
+		}
+
+		field, ok := s.fields[name]
+		if !ok {
+			errs = append(errs, ConfigError{childPath(path, name), "unknown option"})
+			continue
+		}
+
+		errs = append(errs, field.validate(childPath(path, name), v)...)
+	}
+
+	return errs
+}
+
+// linterSchema accepts options of a linter, they depend on its name
+type linterSchema struct {
+	// common are options of every linter besides the name
+	common map[string]valueSchema
+}
+
+func (s linterSchema) validate(path string, v *types.Value) []ConfigError {
+	sv, ok := v.GetKind().(*types.Value_StructValue)
+	if !ok {
+		return []ConfigError{{path, "must be an object"}}
+	}
+
+	fields := sv.StructValue.GetFields()
+	nameV := fields["name"]
+	if nameV == nil {
+		return []ConfigError{{childPath(path, "name"), "is required"}}
+	}
+
+	name := nameV.GetStringValue()
+	if !isKnownLinter(name) {
+		return []ConfigError{{childPath(path, "name"),
+			fmt.Sprintf("unknown linter %q, must be one of %s", name, quoteList(knownLinters()))}}
+	}
+
+	object := objectSchema{fields: map[string]valueSchema{"name": stringSchema{}}}
+	for option, schema := range s.common {
+		object.fields[option] = schema
+	}
+
+	for option, o := range linterOptions(name) {
+		object.fields[option] = o.schema
+	}
+
+	return object.validateFields(path, fields)
+}
+
+// lintersSchema returns the schema of "linters" list, the linters have
+// common options besides the name
+func lintersSchema(common map[string]valueSchema) valueSchema {
+	return listSchema{items: linterSchema{common: common}}
+}
+
+// configSchema is the schema of the analyzer settings of a repository
+var configSchema = objectSchema{fields: map[string]valueSchema{
+	"linters": lintersSchema(map[string]valueSchema{
+		enabledOption: boolSchema{},
+		"exclude":     regexpSchema,
+	}),
+	"include":       regexpSchema,
+	"exclude":       regexpSchema,
+	"skipGenerated": boolSchema{},
+	"generated":     listSchema{items: globSchema},
+	"overrides": listSchema{items: objectSchema{
+		fields: map[string]valueSchema{
+			"paths": listSchema{items: globSchema},
+			"linters": lintersSchema(map[string]valueSchema{
+				enabledOption: boolSchema{},
+			}),
+		},
+		required: []string{"paths"},
+	}},
+	"aggregate":          boolSchema{},
+	"maxCommentsPerFile": intSchema{min: 0},
+	"maxComments":        intSchema{min: 0},
+	"newIssuesOnly":      boolSchema{},
+	"praiseFixed":        boolSchema{},
+	"commentFormat":      enumSchema{string(formatRaw), string(formatMarkdown), string(formatPlain)},
+	"summary":            boolSchema{},
+}}
+
+// ValidateConfig checks the analyzer settings of a repository configuration,
+// the errors have paths of the wrong values
+func ValidateConfig(s types.Struct) []ConfigError {
+	return configSchema.validateFields("", s.GetFields())
+}
+
+// configErrorsComment returns the global comment listing errors
+// of the configuration, nil if there are none
+func configErrorsComment(errs []ConfigError) *pb.Comment {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	text := "The analyzer configuration has errors, the wrong options are ignored:"
+	for _, err := range errs {
+		text += fmt.Sprintf("\n- `%s`: %s", err.Path, err.Message)
+	}
+
+	return &pb.Comment{Text: text}
+}
@@ -0,0 +1,100 @@
+package gometalint
+
+import (
+	"testing"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestValidateConfigValid(t *testing.T) {
+	require := require.New(t)
+
+	require.Empty(ValidateConfig(types.Struct{}))
+	require.Empty(ValidateConfig(*pb.ToStruct(overridesConf)))
+	require.Empty(ValidateConfig(*pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{"name": "lll", "maxLen": "120", "exclude": `_test\.go$`, "enabled": true},
+			{"name": "gocyclo", "over": 15},
+			{"name": "goimports", "localPrefix": "github.com/src-d,gopkg.in/src-d"},
+			{"name": "misspell", "locale": "UK", "ignoreWords": []string{"colour", "flavour"}},
+		},
+		"include":            `^pkg/`,
+		"exclude":            `^pkg/legacy/`,
+		"skipGenerated":      false,
+		"generated":          []string{"*_gen.go"},
+		"aggregate":          false,
+		"maxCommentsPerFile": 0,
+		"maxComments":        "50",
+		"newIssuesOnly":      true,
+		"praiseFixed":        true,
+		"commentFormat":      "markdown",
+		"summary":            true,
+	})))
+}
+
+func TestValidateConfigErrors(t *testing.T) {
+	require := require.New(t)
+
+	errs := ValidateConfig(*pb.ToStruct(map[string]interface{}{
+		"linters": []map[string]interface{}{
+			{"name": "unknown"},
+			{"name": "lll", "maxLen": "not a number", "over": 10},
+			{"name": "gocyclo", "over": 0, "enabled": "no"},
+			{"maxLen": 120},
+			{"name": "goimports", "localPrefix": "github.com/a b"},
+			{"name": "misspell", "locale": "FR", "ignoreWords": []string{"ok", "two words"}},
+		},
+		"include":       "(",
+		"skipGenerated": "yes",
+		"generated":     []string{"[", ""},
+		"overrides": []map[string]interface{}{
+			{"linters": []map[string]interface{}{{"name": "lll", "exclude": "x"}}},
+		},
+		"maxComments":   -1,
+		"commentFormat": "html",
+		"sumary":        true,
+	}))
+
+	var paths []string
+	for _, err := range errs {
+		paths = append(paths, err.Error())
+	}
+
+	require.Equal([]string{
+		"commentFormat: must be one of \"raw\", \"markdown\", \"plain\"",
+		"generated[0]: syntax error in pattern",
+		"generated[1]: must be a non-empty glob",
+		"include: error parsing regexp: missing closing ): `(`",
+		"linters[0].name: unknown linter \"unknown\", must be one of " +
+			"\"dupl\", \"gosec\", \"gofmt\", \"goimports\", \"lll\", \"misspell\", \"gocyclo\"",
+		"linters[1].maxLen: must be an integer",
+		"linters[1].over: unknown option",
+		"linters[2].enabled: must be a boolean",
+		"linters[2].over: must be at least 1",
+		"linters[3].name: is required",
+		"linters[4].localPrefix: must be comma-separated import paths",
+		"linters[5].ignoreWords[1]: must be a word",
+		"linters[5].locale: must be one of \"US\", \"UK\"",
+		"maxComments: must be at least 0",
+		"overrides[0].paths: is required",
+		"overrides[0].linters[0].exclude: unknown option",
+		"skipGenerated: must be a boolean",
+		"sumary: unknown option",
+	}, paths)
+}
+
+func TestConfigErrorsComment(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(configErrorsComment(nil))
+	require.Equal(&pb.Comment{
+		Text: "The analyzer configuration has errors, the wrong options are ignored:\n" +
+			"- `linters[0].maxLen`: must be an integer\n" +
+			"- `summary`: must be a boolean",
+	}, configErrorsComment([]ConfigError{
+		{"linters[0].maxLen", "must be an integer"},
+		{"summary", "must be a boolean"},
+	}))
+}
@@ -0,0 +1,158 @@
+package gometalint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// retries of GetChanges on transient errors, variables to be changed in tests
+var (
+	maxStreamRetries = 5
+	minStreamBackoff = 200 * time.Millisecond
+	maxStreamBackoff = 10 * time.Second
+)
+
+// isTransient returns true for errors of gRPC calls that may succeed if retried
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// changesStream receives changes from the DataService. The request is
+// repeated on transient errors and already received changes are skipped.
+type changesStream struct {
+	ctx    context.Context
+	logger log.Logger
+	client pb.DataClient
+	req    *pb.ChangesRequest
+	stream pb.Data_GetChangesClient
+	// received are keys of received changes, see changeKey
+	received map[string]bool
+	// retries is the number of retries since the last received change
+	retries int
+}
+
+// newChangesStream calls GetChanges, retrying on transient errors
+func newChangesStream(ctx context.Context, logger log.Logger, client pb.DataClient,
+	req *pb.ChangesRequest) (*changesStream, error) {
+
+	s := &changesStream{
+		ctx:      ctx,
+		logger:   logger,
+		client:   client,
+		req:      req,
+		received: make(map[string]bool),
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// open calls GetChanges until it succeeds or fails with a permanent error
+func (s *changesStream) open() error {
+	for {
+		stream, err := s.client.GetChanges(s.ctx, s.req)
+		if err == nil {
+			s.stream = stream
+			return nil
+		}
+
+		if err := s.backoff(err); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff waits before retrying after the error,
+// the error is returned if it must not be retried
+func (s *changesStream) backoff(err error) error {
+	if !isTransient(err) || s.retries >= maxStreamRetries || s.ctx.Err() != nil {
+		return err
+	}
+
+	d := minStreamBackoff << uint(s.retries)
+	if d > maxStreamBackoff || d <= 0 {
+		d = maxStreamBackoff
+	}
+
+	s.retries++
+	s.logger.Warningf("failed to get changes from DataService, retry %d/%d in %s: %s",
+		s.retries, maxStreamRetries, d, err)
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// Recv returns the next change, io.EOF when all changes are received,
+// or the error that can't be recovered from
+func (s *changesStream) Recv() (*pb.Change, error) {
+	for {
+		change, err := s.stream.Recv()
+		if err == io.EOF {
+			return nil, err
+		}
+
+		if err != nil {
+			if err := s.backoff(err); err != nil {
+				return nil, err
+			}
+
+			if err := s.open(); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		key := changeKey(change)
+		if s.received[key] {
+			continue
+		}
+
+		s.received[key] = true
+		s.retries = 0
+		return change, nil
+	}
+}
+
+// changeKey identifies the change by paths of its revisions
+func changeKey(change *pb.Change) string {
+	var base, head string
+	if change.Base != nil {
+		base = change.Base.Path
+	}
+
+	if change.Head != nil {
+		head = change.Head.Path
+	}
+
+	return base + "\x00" + head
+}
+
+// partialComment returns the global comment noting that the analysis
+// is incomplete because the changes could not be received
+func partialComment(err error) *pb.Comment {
+	return &pb.Comment{Text: fmt.Sprintf(
+		"The analysis is partial, not all changed files were received from DataService: %s",
+		status.Convert(err).Message())}
+}
@@ -0,0 +1,132 @@
+package gometalint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/src-d/lookout-gometalint-analyzer/datatest"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// withFastRetries makes retries of streams instant
+func withFastRetries() func() {
+	origMin, origMax := minStreamBackoff, maxStreamBackoff
+	minStreamBackoff, maxStreamBackoff = time.Millisecond, time.Millisecond
+	return func() {
+		minStreamBackoff, maxStreamBackoff = origMin, origMax
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	require := require.New(t)
+
+	require.True(isTransient(status.Error(codes.Unavailable, "unavailable")))
+	require.True(isTransient(status.Error(codes.ResourceExhausted, "exhausted")))
+	require.False(isTransient(status.Error(codes.InvalidArgument, "invalid")))
+	require.False(isTransient(status.Error(codes.Canceled, "canceled")))
+	require.False(isTransient(errors.New("unknown")))
+}
+
+func receivedPaths(t *testing.T, s *changesStream) ([]string, error) {
+	var paths []string
+	for {
+		change, err := s.Recv()
+		if err == io.EOF {
+			return paths, nil
+		}
+
+		if err != nil {
+			return paths, err
+		}
+
+		paths = append(paths, change.Head.Path)
+	}
+}
+
+func TestChangesStream(t *testing.T) {
+	defer withFastRetries()()
+
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	invalid := status.Error(codes.InvalidArgument, "invalid")
+
+	tests := []struct {
+		name     string
+		failures []datatest.Failure
+		paths    []string
+		err      error
+		calls    int
+	}{
+		{"no failures", nil, []string{"a.go", "b.go", "c.go"}, nil, 1},
+		{"transient call", []datatest.Failure{
+			{After: -1, Err: unavailable},
+		}, []string{"a.go", "b.go", "c.go"}, nil, 2},
+		{"transient stream", []datatest.Failure{
+			{After: 1, Err: unavailable},
+			{After: 2, Err: unavailable},
+		}, []string{"a.go", "b.go", "c.go"}, nil, 3},
+		{"permanent stream", []datatest.Failure{
+			{After: 2, Err: invalid},
+		}, []string{"a.go", "b.go"}, invalid, 1},
+		{"retries exhausted", []datatest.Failure{
+			{After: 1, Err: unavailable},
+			{After: 1, Err: unavailable},
+			{After: 1, Err: unavailable},
+			{After: 1, Err: unavailable},
+			{After: 1, Err: unavailable},
+			{After: 1, Err: unavailable},
+		}, []string{"a.go"}, unavailable, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			client := datatest.NewDataClient(
+				&pb.Change{Head: datatest.File("a.go", "package a\n")},
+				&pb.Change{Head: datatest.File("b.go", "package b\n")},
+				&pb.Change{Head: datatest.File("c.go", "package c\n")},
+			)
+			client.ChangesFailures = tt.failures
+
+			s, err := newChangesStream(context.Background(), logger, client, &pb.ChangesRequest{})
+			require.NoError(err)
+
+			paths, err := receivedPaths(t, s)
+			require.Equal(tt.err, err)
+			require.Equal(tt.paths, paths)
+			require.Len(client.ChangesRequests(), tt.calls)
+		})
+	}
+}
+
+func TestChangesStreamCallFailed(t *testing.T) {
+	defer withFastRetries()()
+
+	invalid := status.Error(codes.InvalidArgument, "invalid")
+	client := datatest.NewDataClient()
+	client.ChangesFailures = []datatest.Failure{{After: -1, Err: invalid}}
+
+	_, err := newChangesStream(context.Background(), logger, client, &pb.ChangesRequest{})
+	require.Equal(t, invalid, err)
+	require.Len(t, client.ChangesRequests(), 1)
+}
+
+func TestChangesStreamCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := datatest.NewDataClient(&pb.Change{Head: datatest.File("a.go", "package a\n")})
+	client.ChangesFailures = []datatest.Failure{{After: 0, Err: status.Error(codes.Unavailable, "")}}
+
+	s, err := newChangesStream(ctx, logger, client, &pb.ChangesRequest{})
+	require.NoError(t, err)
+
+	cancel()
+	_, err = s.Recv()
+	require.Equal(t, context.Canceled, err)
+}
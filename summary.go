@@ -0,0 +1,154 @@
+package gometalint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	types "github.com/gogo/protobuf/types"
+	log "gopkg.in/src-d/go-log.v1"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// reviewSummary collects statistics of a review
+type reviewSummary struct {
+	analyzed int
+	// skipped counts files not analyzed by reason
+	skipped map[string]int
+	// reasons are skip reasons in order of appearance
+	reasons []string
+	// issues counts issues by linter and severity
+	issues    map[string]map[string]int
+	newIssues int
+	oldIssues int
+	// fixed is the number of issues fixed in the head revision
+	fixed    int
+	lintTime time.Duration
+}
+
+// reasons files are skipped for
+const (
+	skippedGenerated = "generated"
+	skippedExcluded  = "excluded"
+	skippedNotSaved  = "not saved"
+)
+
+func newReviewSummary() *reviewSummary {
+	return &reviewSummary{
+		skipped: make(map[string]int),
+		issues:  make(map[string]map[string]int),
+	}
+}
+
+// summaryConfig reads "summary" option of the configuration,
+// the summary is disabled by default
+func summaryConfig(logger log.Logger, s types.Struct) bool {
+	v, ok := s.GetFields()["summary"]
+	if !ok || v == nil {
+		return false
+	}
+
+	b, ok := v.GetKind().(*types.Value_BoolValue)
+	if !ok {
+		logger.Warningf("wrong type for summary argument")
+		return false
+	}
+
+	return b.BoolValue
+}
+
+// skip counts the file skipped for the reason
+func (s *reviewSummary) skip(reason string) {
+	if s.skipped[reason] == 0 {
+		s.reasons = append(s.reasons, reason)
+	}
+
+	s.skipped[reason]++
+}
+
+// addIssues counts new and pre-existing issues
+func (s *reviewSummary) addIssues(newIssues, oldIssues []Comment) {
+	for _, issues := range [][]Comment{newIssues, oldIssues} {
+		for _, issue := range issues {
+			linter := issue.Linter()
+			if s.issues[linter] == nil {
+				s.issues[linter] = make(map[string]int)
+			}
+			s.issues[linter][issue.level]++
+		}
+	}
+
+	s.newIssues += len(newIssues)
+	s.oldIssues += len(oldIssues)
+}
+
+// comment returns the global comment with the summary
+func (s *reviewSummary) comment() *pb.Comment {
+	skipped := 0
+	var details []string
+	for _, reason := range s.reasons {
+		skipped += s.skipped[reason]
+		details = append(details, fmt.Sprintf("%s: %d", reason, s.skipped[reason]))
+	}
+
+	files := fmt.Sprintf("- files: %d analyzed, %d skipped", s.analyzed, skipped)
+	if len(details) > 0 {
+		files += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	lines := []string{
+		"gometalint summary:",
+		files,
+		fmt.Sprintf("- issues: %d new, %d pre-existing",
+			s.newIssues, s.oldIssues),
+	}
+
+	var linters []string
+	for linter := range s.issues {
+		linters = append(linters, linter)
+	}
+	sort.Strings(linters)
+
+	for _, linter := range linters {
+		var severities []string
+		for severity := range s.issues[linter] {
+			severities = append(severities, severity)
+		}
+		sort.Strings(severities)
+
+		var counts []string
+		for _, severity := range severities {
+			counts = append(counts, fmt.Sprintf("%d %s", s.issues[linter][severity], severity))
+		}
+
+		name := linter
+		if name == "" {
+			name = "unknown"
+		}
+
+		lines = append(lines, fmt.Sprintf("  - `%s`: %s", name, strings.Join(counts, ", ")))
+	}
+
+	if s.fixed > 0 {
+		lines = append(lines, "- "+s.praise())
+	}
+
+	lines = append(lines, fmt.Sprintf("- lint time: %s", s.lintTime.Round(time.Millisecond)))
+	return &pb.Comment{Text: strings.Join(lines, "\n")}
+}
+
+// praise returns the message thanking for fixed issues
+func (s *reviewSummary) praise() string {
+	return fmt.Sprintf("%d issues are fixed, thank you!", s.fixed)
+}
+
+// praiseComment returns the global comment thanking for fixed issues,
+// nil if nothing was fixed
+func (s *reviewSummary) praiseComment() *pb.Comment {
+	if s.fixed == 0 {
+		return nil
+	}
+
+	return &pb.Comment{Text: s.praise()}
+}
@@ -0,0 +1,57 @@
+package gometalint
+
+import (
+	"testing"
+	"time"
+
+	types "github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func TestSummaryConfig(t *testing.T) {
+	require := require.New(t)
+
+	require.False(summaryConfig(logger, types.Struct{}))
+	require.True(summaryConfig(logger, *pb.ToStruct(map[string]interface{}{"summary": true})))
+	require.False(summaryConfig(logger, *pb.ToStruct(map[string]interface{}{"summary": "yes"})))
+}
+
+func TestSummaryComment(t *testing.T) {
+	require := require.New(t)
+
+	bases := newBaseIndex()
+	bases.add(&pb.Change{
+		Base: &pb.File{Path: "a.go", Content: []byte("package a\n")},
+		Head: &pb.File{Path: "a.go", Content: []byte("package a\n\nvar a = 1\n")},
+	})
+
+	s := newReviewSummary()
+	s.analyzed = 2
+	s.skip(skippedGenerated)
+	s.skip(skippedNotSaved)
+	s.skip(skippedGenerated)
+	s.lintTime = 1234567 * time.Microsecond
+	s.addIssues(splitByBase([]Comment{
+		NewComment("warning", "a.go", 1, 0, " wrong package (golint)"),
+		NewComment("warning", "a.go", 3, 0, " line is 130 characters (lll)"),
+		NewComment("error", "a.go", 3, 0, " unsafe (gosec)"),
+		NewComment("warning", "b.go", 1, 0, " unsafe (gosec)"),
+		NewComment("warning", "b.go", 1, 0, " unsafe (gosec)"),
+		NewComment("error", "b.go", 1, 0, " no linter"),
+	}, bases))
+
+	require.Equal(&pb.Comment{Text: "gometalint summary:\n" +
+		"- files: 2 analyzed, 3 skipped (generated: 2, not saved: 1)\n" +
+		"- issues: 5 new, 1 pre-existing\n" +
+		"  - `unknown`: 1 error\n" +
+		"  - `golint`: 1 warning\n" +
+		"  - `gosec`: 1 error, 2 warning\n" +
+		"  - `lll`: 1 warning\n" +
+		"- lint time: 1.235s"}, s.comment())
+
+	require.Equal(&pb.Comment{Text: "gometalint summary:\n" +
+		"- files: 0 analyzed, 0 skipped\n" +
+		"- issues: 0 new, 0 pre-existing\n" +
+		"- lint time: 0s"}, newReviewSummary().comment())
+}
@@ -0,0 +1,261 @@
+package gometalint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+// maxNameLen is the maximum length of a file name on most file systems
+const maxNameLen = 255
+
+// git file modes, see pb.File.Mode
+const (
+	modeTypeMask  = 0170000
+	modeRegular   = 0100000
+	modeSymlink   = 0120000
+	modeSubmodule = 0160000
+)
+
+// maxSkippedListed is the maximum number of skipped files listed in a comment
+const maxSkippedListed = 50
+
+// Limits restrict files saved for analysis in one review, 0 means no limit
+type Limits struct {
+	// MaxFileSize is the maximum size of a file in bytes
+	MaxFileSize int64
+	// MaxTotalSize is the maximum size of all files in bytes
+	MaxTotalSize int64
+	// MaxFiles is the maximum number of files
+	MaxFiles int
+}
+
+// skippedFile is a file that was not saved to the workspace
+type skippedFile struct {
+	path   string
+	reason string
+}
+
+// workspace is a temporary directory files under review are saved to.
+// The directory is a GOPATH, files are saved to their paths under the root
+// directory of the import path of the repository, see layout.go.
+type workspace struct {
+	dir    string
+	root   string
+	limits Limits
+	// totalSize is the size of all saved files
+	totalSize int64
+	// saved maps lower-cased paths to original ones,
+	// to detect collisions on case-insensitive file systems as well
+	saved   map[string]string
+	skipped []skippedFile
+}
+
+// newWorkspace creates a workspace in a new temporary directory,
+// files are saved under the import path
+func newWorkspace(limits Limits, importPath string) (*workspace, error) {
+	dir, err := ioutil.TempDir("", "gometalint")
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(dir, "src", filepath.FromSlash(importPath))
+	if err := os.MkdirAll(root, 0755); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return &workspace{dir: dir, root: root, limits: limits, saved: make(map[string]string)}, nil
+}
+
+// Close removes the workspace directory with all the files
+func (w *workspace) Close() error {
+	return os.RemoveAll(w.dir)
+}
+
+// Save writes the file to the workspace. Unsafe files are not written,
+// they are recorded as skipped and the error is returned.
+func (w *workspace) Save(file *pb.File) error {
+	if err := w.Reserve(file); err != nil {
+		return err
+	}
+
+	return w.Write(file)
+}
+
+// Reserve checks that the file can be saved and counts it against the limits
+// without writing it, Write writes it later. Unsafe files are recorded
+// as skipped and the error is returned.
+func (w *workspace) Reserve(file *pb.File) error {
+	err := w.reserve(file)
+	if err != nil {
+		w.skipped = append(w.skipped, skippedFile{path: file.Path, reason: err.Error()})
+	}
+
+	return err
+}
+
+func (w *workspace) reserve(file *pb.File) error {
+	if err := checkMode(file.Mode); err != nil {
+		return err
+	}
+
+	p, err := cleanPath(file.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range strings.Split(p, "/") {
+		if len(name) > maxNameLen {
+			return fmt.Errorf("path is too long")
+		}
+	}
+
+	key := strings.ToLower(p)
+	if orig, ok := w.saved[key]; ok {
+		return fmt.Errorf("path collides with %q", orig)
+	}
+
+	if err := w.checkLimits(int64(len(file.Content))); err != nil {
+		return err
+	}
+
+	w.saved[key] = p
+	w.totalSize += int64(len(file.Content))
+	return nil
+}
+
+// Write writes the reserved file to the workspace. If it fails,
+// the file is recorded as skipped and the error is returned.
+func (w *workspace) Write(file *pb.File) error {
+	err := w.write(file)
+	if err != nil {
+		w.skipped = append(w.skipped, skippedFile{path: file.Path, reason: err.Error()})
+	}
+
+	return err
+}
+
+func (w *workspace) write(file *pb.File) error {
+	// the path is clean and nothing but directories and regular files
+	// is created, so parents can't lead outside of the workspace
+	fullPath := w.path(path.Clean(file.Path))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+
+	// O_EXCL guarantees nothing that already exists is overwritten or followed
+	f, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(file.Content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(fullPath)
+	}
+
+	return err
+}
+
+// path returns the path in the workspace of the slash-separated relative path
+func (w *workspace) path(p string) string {
+	return filepath.Join(w.root, filepath.FromSlash(p))
+}
+
+// checkLimits returns error if a file of the given size can't be saved
+func (w *workspace) checkLimits(size int64) error {
+	l := w.limits
+	if l.MaxFileSize > 0 && size > l.MaxFileSize {
+		return fmt.Errorf("file is larger than %d bytes", l.MaxFileSize)
+	}
+
+	if l.MaxFiles > 0 && len(w.saved) >= l.MaxFiles {
+		return fmt.Errorf("limit of %d files is reached", l.MaxFiles)
+	}
+
+	if l.MaxTotalSize > 0 && w.totalSize+size > l.MaxTotalSize {
+		return fmt.Errorf("limit of %d bytes in total is reached", l.MaxTotalSize)
+	}
+
+	return nil
+}
+
+// Skipped returns files that were not saved
+func (w *workspace) Skipped() []skippedFile {
+	return w.skipped
+}
+
+// skippedComment returns a global comment listing skipped files,
+// nil if nothing was skipped
+func skippedComment(skipped []skippedFile) *pb.Comment {
+	if len(skipped) == 0 {
+		return nil
+	}
+
+	lines := []string{fmt.Sprintf("%d files were not analyzed:", len(skipped))}
+	for i, f := range skipped {
+		if i == maxSkippedListed {
+			lines = append(lines, fmt.Sprintf("- and %d more", len(skipped)-i))
+			break
+		}
+
+		lines = append(lines, fmt.Sprintf("- `%s`: %s", f.path, f.reason))
+	}
+
+	return &pb.Comment{Text: strings.Join(lines, "\n")}
+}
+
+// checkMode returns error for git modes of anything but regular files.
+// Zero mode is allowed, as the DataService may not set it.
+func checkMode(mode uint32) error {
+	switch mode & modeTypeMask {
+	case 0, modeRegular:
+		return nil
+	case modeSymlink:
+		return fmt.Errorf("symbolic links are not supported")
+	case modeSubmodule:
+		return fmt.Errorf("submodules are not supported")
+	default:
+		return fmt.Errorf("unsupported file mode %o", mode)
+	}
+}
+
+// cleanPath validates a slash-separated relative path
+// and returns it in canonical form.
+func cleanPath(p string) (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("empty path")
+	}
+
+	// ':' and new lines break parsing of gometalint output
+	if strings.ContainsAny(p, "\x00\\:\r\n") {
+		return "", fmt.Errorf("path contains forbidden characters")
+	}
+
+	if path.IsAbs(p) || filepath.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return "", fmt.Errorf("path is absolute")
+	}
+
+	for _, segment := range strings.Split(p, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("path contains '..'")
+		}
+	}
+
+	clean := path.Clean(p)
+	if clean == "." || strings.HasSuffix(p, "/") {
+		return "", fmt.Errorf("path is not a file")
+	}
+
+	return clean, nil
+}
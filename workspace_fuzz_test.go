@@ -0,0 +1,74 @@
+//go:build go1.18
+// +build go1.18
+
+package gometalint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+func FuzzWorkspaceSave(f *testing.F) {
+	for _, tt := range cleanPathTests {
+		f.Add(tt.in, tt.in)
+	}
+	f.Add("a/b.go", "a")
+	f.Add("a/b.go", "A/b.go")
+
+	f.Fuzz(func(t *testing.T, first, second string) {
+		ws, err := newWorkspace(Limits{}, defaultImportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ws.Close()
+
+		saved := make(map[string]bool)
+		for _, p := range []string{first, second} {
+			if err := ws.Save(&pb.File{Path: p, Content: []byte(p)}); err != nil {
+				continue
+			}
+
+			clean, err := cleanPath(p)
+			if err != nil {
+				t.Fatalf("saved invalid path %q", p)
+			}
+
+			full := ws.path(clean)
+			if !strings.HasPrefix(full, ws.root+string(filepath.Separator)) {
+				t.Fatalf("path %q is saved outside of workspace as %q", p, full)
+			}
+
+			if orig := revertOriginalPath(full, ws.root); orig != clean {
+				t.Fatalf("path %q is reverted as %q, expected %q", p, orig, clean)
+			}
+
+			content, err := ioutil.ReadFile(full)
+			if err != nil || string(content) != p {
+				t.Fatalf("content of %q is not saved: %v", p, err)
+			}
+
+			saved[full] = true
+		}
+
+		files := 0
+		err = filepath.Walk(ws.dir, func(p string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				files++
+			}
+
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if files != len(saved) {
+			t.Fatalf("%d files in workspace, %d saved", files, len(saved))
+		}
+	})
+}
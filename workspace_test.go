@@ -0,0 +1,167 @@
+package gometalint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/lookout-sdk.v0/pb"
+)
+
+var cleanPathTests = []struct {
+	in    string
+	out   string
+	valid bool
+}{
+	{"a.go", "a.go", true},
+	{"a/b.go", "a/b.go", true},
+	{"./a//b.go", "a/b.go", true},
+	{"a/./b.go", "a/b.go", true},
+	{"", "", false},
+	{".", "", false},
+	{"a/", "", false},
+	{"/etc/passwd", "", false},
+	{"../a.go", "", false},
+	{"a/../../b.go", "", false},
+	{"a/../b.go", "", false},
+	{"a/..", "", false},
+	{"a___.___b.go", "a___.___b.go", true},
+	{"a\\b.go", "", false},
+	{"a\x00.go", "", false},
+	{"a:1:b.go", "", false},
+	{"a\nb.go", "", false},
+}
+
+func TestCleanPath(t *testing.T) {
+	for _, tt := range cleanPathTests {
+		t.Run(tt.in, func(t *testing.T) {
+			out, err := cleanPath(tt.in)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestCheckMode(t *testing.T) {
+	assert.NoError(t, checkMode(0))
+	assert.NoError(t, checkMode(0100644))
+	assert.NoError(t, checkMode(0100755))
+	assert.Error(t, checkMode(0120000))
+	assert.Error(t, checkMode(0160000))
+	assert.Error(t, checkMode(0040000))
+}
+
+func TestWorkspaceSave(t *testing.T) {
+	require := require.New(t)
+
+	ws, err := newWorkspace(Limits{}, "github.com/src-d/lookout")
+	require.NoError(err)
+	defer ws.Close()
+	require.Equal(filepath.Join(ws.dir, "src", "github.com", "src-d", "lookout"), ws.root)
+
+	files := []*pb.File{
+		{Path: "a/b.go", Content: []byte("package a\n")},
+		{Path: "./a//b.go", Content: []byte("package dup\n")},
+		{Path: "A/B.go", Content: []byte("package a\n")},
+		{Path: "../escape.go", Content: []byte("package escape\n")},
+		{Path: "link.go", Mode: 0120000, Content: []byte("/etc/passwd")},
+		{Path: strings.Repeat("a", 256) + "/b.go"},
+		{Path: strings.Repeat("a/", 200) + "b.go"},
+		{Path: "c.go", Mode: 0100644, Content: []byte("package c\n")},
+		{Path: "c.go/d.go", Content: []byte("package d\n")},
+	}
+
+	var saved []string
+	for _, f := range files {
+		if ws.Save(f) == nil {
+			saved = append(saved, f.Path)
+		}
+	}
+
+	require.Equal([]string{"a/b.go", strings.Repeat("a/", 200) + "b.go", "c.go"}, saved)
+
+	var skipped []string
+	for _, f := range ws.Skipped() {
+		skipped = append(skipped, f.path)
+		require.NotEmpty(f.reason)
+	}
+	require.Equal([]string{"./a//b.go", "A/B.go", "../escape.go", "link.go",
+		strings.Repeat("a", 256) + "/b.go", "c.go/d.go"}, skipped)
+
+	infos, err := ioutil.ReadDir(ws.root)
+	require.NoError(err)
+	require.Len(infos, 2)
+
+	content, err := ioutil.ReadFile(filepath.Join(ws.root, "a", "b.go"))
+	require.NoError(err)
+	require.Equal("package a\n", string(content))
+	require.Equal(filepath.Join(ws.root, "a", "b.go"), ws.path("a/b.go"))
+
+	_, err = os.Stat(filepath.Join(ws.root, "c.go"))
+	require.NoError(err)
+}
+
+func TestWorkspaceEnv(t *testing.T) {
+	require := require.New(t)
+
+	ws, err := newWorkspace(Limits{}, defaultImportPath)
+	require.NoError(err)
+	defer ws.Close()
+
+	env := ws.env()
+	require.Len(env, 3)
+	require.True(strings.HasPrefix(env[0], "GOPATH="+ws.dir))
+	require.Equal([]string{"GO111MODULE=off", "GOFLAGS="}, env[1:])
+}
+
+func TestWorkspaceLimits(t *testing.T) {
+	require := require.New(t)
+
+	ws, err := newWorkspace(Limits{MaxFileSize: 10, MaxTotalSize: 15, MaxFiles: 3}, defaultImportPath)
+	require.NoError(err)
+	defer ws.Close()
+
+	require.NoError(ws.Save(&pb.File{Path: "a.go", Content: []byte("12345678")}))
+	require.Error(ws.Save(&pb.File{Path: "big.go", Content: []byte("12345678901")}))
+	require.Error(ws.Save(&pb.File{Path: "total.go", Content: []byte("12345678")}))
+	require.NoError(ws.Save(&pb.File{Path: "b.go", Content: []byte("1234567")}))
+	require.Error(ws.Save(&pb.File{Path: "c.go", Content: []byte("1")}), "total size is reached")
+
+	ws, err = newWorkspace(Limits{MaxFiles: 1}, defaultImportPath)
+	require.NoError(err)
+	defer ws.Close()
+
+	require.NoError(ws.Save(&pb.File{Path: "a.go"}))
+	require.EqualError(ws.Save(&pb.File{Path: "b.go"}), "limit of 1 files is reached")
+}
+
+func TestSkippedComment(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(skippedComment(nil))
+	require.Equal(&pb.Comment{Text: "2 files were not analyzed:\n" +
+		"- `a.go`: file is larger than 10 bytes\n" +
+		"- `link.go`: symbolic links are not supported"},
+		skippedComment([]skippedFile{
+			{"a.go", "file is larger than 10 bytes"},
+			{"link.go", "symbolic links are not supported"},
+		}))
+
+	var skipped []skippedFile
+	for i := 0; i < maxSkippedListed+2; i++ {
+		skipped = append(skipped, skippedFile{"a.go", "limit of 1 files is reached"})
+	}
+
+	lines := strings.Split(skippedComment(skipped).Text, "\n")
+	require.Len(lines, maxSkippedListed+2)
+	require.Equal("- and 2 more", lines[len(lines)-1])
+}